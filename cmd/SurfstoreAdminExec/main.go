@@ -0,0 +1,186 @@
+package main
+
+import (
+	"cse224/proj4/pkg/surfstore"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Arguments
+const MIN_ARG_COUNT int = 2
+
+// Usage strings
+const USAGE_STRING = "./run-admin.sh -d [-grace duration] host:port command [args]"
+
+const DEBUG_NAME = "d"
+const DEBUG_USAGE = "Output log statements"
+
+const GRACE_NAME = "grace"
+const GRACE_USAGE = "(gc) Keep unreferenced blocks written more recently than this"
+
+const ADDR_NAME = "host:port"
+const ADDR_USAGE = "IP address and port of the MetaStore (comma separated for a raft cluster)"
+
+const COMMAND_NAME = "command"
+const COMMAND_USAGE = `one of
+    gc: delete the blocks no file references and report the bytes reclaimed
+    add-blockstore blockStoreAddr[,weight=n][,zone=name]: add a BlockStore to the ring and move its blocks onto it
+    remove-blockstore blockStoreAddr: move the blocks off a BlockStore and take it off the ring
+    set-address nodeId blockStoreAddr: the BlockStore with node ID nodeId moved to blockStoreAddr, no block moves
+    status: whether every BlockStore answers the MetaStore, exits with 1 if one is down
+    repair-status: how anti-entropy is going on every BlockStore`
+
+// Exit codes
+const EX_USAGE int = 64
+
+func main() {
+	// Custom flag Usage message
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", GRACE_NAME, GRACE_USAGE)
+		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
+		fmt.Fprintf(w, "  %s: %v\n", COMMAND_NAME, COMMAND_USAGE)
+	}
+
+	// Parse command-line arguments and flags
+	debug := flag.Bool(DEBUG_NAME, false, DEBUG_USAGE)
+	grace := flag.Duration(GRACE_NAME, surfstore.DEFAULT_GC_GRACE_PERIOD, GRACE_USAGE)
+	flag.Parse()
+
+	// Use tail arguments to hold non-flag arguments
+	args := flag.Args()
+
+	if len(args) < MIN_ARG_COUNT {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	hostPort := args[0]
+	command := args[1]
+
+	// Disable log outputs if debug flag is missing
+	if !(*debug) {
+		log.SetFlags(0)
+		log.SetOutput(io.Discard)
+	}
+
+	rpcClient := surfstore.NewAdminRPCClient(hostPort)
+	switch command {
+	case "gc":
+		CollectGarbage(rpcClient, *grace)
+	case "add-blockstore", "remove-blockstore":
+		if len(args) != MIN_ARG_COUNT+1 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		blockStoreAddr, err := surfstore.ParseBlockStoreArg(args[2])
+		if err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), err)
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		ChangeBlockStores(rpcClient, command == "add-blockstore", blockStoreAddr)
+	case "set-address":
+		if len(args) != MIN_ARG_COUNT+2 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		if err := rpcClient.SetBlockStoreAddr(args[2], args[3]); err != nil {
+			fmt.Fprintln(os.Stderr, "set-address failed:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is now at %s\n", args[2], args[3])
+	case "status":
+		PrintClusterStatus(rpcClient)
+	case "repair-status":
+		PrintRepairStatus(rpcClient)
+	default:
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+}
+
+func CollectGarbage(client surfstore.RPCClient, grace time.Duration) {
+	report := &surfstore.GarbageCollectionReport{}
+	if err := client.CollectGarbage(grace, report); err != nil {
+		fmt.Fprintln(os.Stderr, "garbage collection failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("scanned %d blocks, deleted %d blocks, reclaimed %d bytes\n",
+		report.BlocksScanned, report.BlocksDeleted, report.BytesReclaimed)
+}
+
+func ChangeBlockStores(client surfstore.RPCClient, add bool, blockStoreAddr *surfstore.BlockStoreAddr) {
+	report := &surfstore.MigrationReport{}
+	var err error
+	if add {
+		err = client.AddBlockStore(blockStoreAddr.Addr, blockStoreAddr.Weight, blockStoreAddr.Zone, report)
+	} else {
+		err = client.RemoveBlockStore(blockStoreAddr.Addr, report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migration failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("copied %d blocks, %d bytes\n", report.BlocksCopied, report.BytesCopied)
+}
+
+func PrintClusterStatus(client surfstore.RPCClient) {
+	blockStores := []*surfstore.BlockStoreStatus{}
+	if err := client.GetClusterStatus(&blockStores); err != nil {
+		fmt.Fprintln(os.Stderr, "status failed:", err)
+		os.Exit(1)
+	}
+	down := false
+	fmt.Printf("%-24s %-8s %-8s %-10s %s\n", "server", "zone", "status", "last seen", "error")
+	for _, blockStore := range blockStores {
+		lastSeen := "never"
+		if blockStore.LastSeen > 0 {
+			lastSeen = time.Since(time.UnixMilli(blockStore.LastSeen)).Round(100*time.Millisecond).String() + " ago"
+		}
+		zone := blockStore.Zone
+		if zone == "" {
+			zone = "-"
+		}
+		fmt.Printf("%-24s %-8s %-8s %-10s %s\n", blockStore.Addr, zone, blockStore.Status, lastSeen, blockStore.Error)
+		down = down || blockStore.Status == surfstore.BLOCKSTORE_DOWN
+	}
+	if down {
+		os.Exit(1)
+	}
+}
+
+func PrintRepairStatus(client surfstore.RPCClient) {
+	blockStoreAddrs := []string{}
+	if err := client.GetBlockStoreAddrs(&blockStoreAddrs); err != nil {
+		fmt.Fprintln(os.Stderr, "repair-status failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%-24s %-8s %-6s %-12s %10s %10s %s\n", "server", "state", "peers", "last repair", "repaired", "total", "error")
+	for _, addr := range blockStoreAddrs {
+		repairStatus := &surfstore.RepairStatus{}
+		if err := client.GetRepairStatus(addr, repairStatus); err != nil {
+			fmt.Printf("%-24s %-8s %-6s %-12s %10s %10s %v\n", addr, "-", "-", "-", "-", "-", err)
+			continue
+		}
+		state := "idle"
+		if !repairStatus.Enabled {
+			state = "off"
+		} else if repairStatus.Running {
+			state = "running"
+		}
+		lastRepair := "never"
+		if repairStatus.LastRepair > 0 {
+			lastRepair = time.Since(time.UnixMilli(repairStatus.LastRepair)).Round(time.Second).String() + " ago"
+		}
+		fmt.Printf("%-24s %-8s %-6s %-12s %10d %10d %s\n", addr, state,
+			fmt.Sprintf("%d/%d", repairStatus.PeersDone, repairStatus.Peers), lastRepair,
+			repairStatus.BlocksRepaired, repairStatus.BlocksRepairedTotal, repairStatus.Error)
+	}
+}
@@ -14,13 +14,19 @@ import (
 const ARG_COUNT int = 3
 
 // Usage strings
-const USAGE_STRING = "./run-client.sh -d host:port baseDir blockSize"
+const USAGE_STRING = "./run-client.sh -d [-paranoid] [-j n] host:port baseDir blockSize"
 
 const DEBUG_NAME = "d"
 const DEBUG_USAGE = "Output log statements"
 
+const PARANOID_NAME = "paranoid"
+const PARANOID_USAGE = "Hash every file, not only the files whose size, modification time or inode changed since the last sync"
+
+const JOBS_NAME = "j"
+const JOBS_USAGE = "How many files to hash, and how many block servers to upload to, at the same time"
+
 const ADDR_NAME = "host:port"
-const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to"
+const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to (comma separated for a raft cluster)"
 
 const BASEDIR_NAME = "baseDir"
 const BASEDIR_USAGE = "Base directory of the client"
@@ -37,6 +43,8 @@ func main() {
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
 		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", PARANOID_NAME, PARANOID_USAGE)
+		fmt.Fprintf(w, "  -%s: %v (default %d)\n", JOBS_NAME, JOBS_USAGE, surfstore.DEFAULT_CLIENT_JOBS)
 		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BLOCK_NAME, BLOCK_USAGE)
@@ -44,13 +52,15 @@ func main() {
 
 	// Parse command-line arguments and flags
 	debug := flag.Bool("d", false, DEBUG_USAGE)
+	paranoid := flag.Bool(PARANOID_NAME, false, PARANOID_USAGE)
+	jobs := flag.Int(JOBS_NAME, surfstore.DEFAULT_CLIENT_JOBS, JOBS_USAGE)
 	flag.Parse()
 
 	// Use tail arguments to hold non-flag arguments
 	args := flag.Args()
 
 	// ARG_COUNT = 3, including: host:port, baseDir, blockSize
-	if len(args) != ARG_COUNT {
+	if len(args) != ARG_COUNT || *jobs < 1 {
 		flag.Usage()
 		os.Exit(EX_USAGE)
 	}
@@ -74,6 +84,8 @@ func main() {
 
 	// Create a new SurfstoreRPCClient
 	rpcClient := surfstore.NewSurfstoreRPCClient(hostPort, baseDir, blockSize)
+	rpcClient.Paranoid = *paranoid
+	rpcClient.Jobs = *jobs
 
 	// ClientSync: Sync the client with the MetaStore
 	surfstore.ClientSync(rpcClient)
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"cse224/proj4/pkg/surfstore"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Arguments
+const ARG_COUNT int = 1
+
+// Usage strings
+const USAGE_STRING = "./run-index-check.sh baseDir"
+
+const BASEDIR_NAME = "baseDir"
+const BASEDIR_USAGE = "Base directory of the client whose index.db to check, it is not changed"
+
+// Exit codes
+const EX_USAGE int = 64
+
+func main() {
+	// Custom flag Usage message
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
+		fmt.Fprintf(w, "Exits with 1 if the index has problems\n")
+	}
+	flag.Parse()
+
+	// Use tail arguments to hold non-flag arguments
+	args := flag.Args()
+
+	if len(args) != ARG_COUNT {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	version, problems, err := surfstore.CheckMetaFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "index check failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("schema version %d\n", version)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
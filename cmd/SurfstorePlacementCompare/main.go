@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"cse224/proj4/pkg/surfstore"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Usage strings
+const USAGE_STRING = "./run-placement-compare.sh [-servers n] [-vnodes n] [-r n] [-blocks n | -hashes file]"
+
+const SERVERS_USAGE = "BlockStores before the membership change"
+const VNODES_USAGE = "Virtual nodes per BlockStore, for the strategies built on the ring"
+const REPLICATION_USAGE = "Replicas of each block"
+const BLOCKS_USAGE = "Block hashes to place, made up, when -hashes is not given"
+const HASHES_USAGE = "File with the block hashes to place, one per line"
+
+// Exit codes
+const EX_USAGE int = 64
+
+var STRATEGIES = []string{surfstore.PLACEMENT_CONSISTENT, surfstore.PLACEMENT_RENDEZVOUS, surfstore.PLACEMENT_BOUNDED}
+
+// Places the same block hashes with every placement strategy, and prints how evenly they land on
+// the BlockStores and how many move when one BlockStore is added and when one is removed
+func main() {
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		flag.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "  -%s: %v\n", f.Name, f.Usage)
+		})
+	}
+	servers := flag.Int("servers", 10, SERVERS_USAGE)
+	virtualNodes := flag.Int("vnodes", surfstore.DEFAULT_VIRTUAL_NODES, VNODES_USAGE)
+	replication := flag.Int("r", surfstore.DEFAULT_REPLICATION_FACTOR, REPLICATION_USAGE)
+	blocks := flag.Int("blocks", 100000, BLOCKS_USAGE)
+	hashesFile := flag.String("hashes", "", HASHES_USAGE)
+	flag.Parse()
+
+	if *servers < 2 || *virtualNodes < 1 || *replication < 1 || *blocks < 1 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	blockHashes := []string{}
+	if *hashesFile != "" {
+		var err error
+		if blockHashes, err = readHashes(*hashesFile); err != nil {
+			log.Fatalf("Error reading %s: %v", *hashesFile, err)
+		}
+	} else {
+		for i := 0; i < *blocks; i++ {
+			blockHashes = append(blockHashes, surfstore.GetBlockHashString([]byte(strconv.Itoa(i))))
+		}
+	}
+
+	addrs := make([]string, *servers+1)
+	for i := range addrs {
+		addrs[i] = "localhost:" + strconv.Itoa(8081+i)
+	}
+	before := addrs[:*servers]
+	added := addrs
+	removed := addrs[1:*servers]
+
+	fmt.Printf("%d blocks, %d servers, %d virtual nodes, %d replicas\n", len(blockHashes), *servers, *virtualNodes, *replication)
+	fmt.Printf("%-11s %8s %8s %8s %12s %12s %12s %12s\n", "strategy", "max/avg", "min/avg", "stddev",
+		"add moved", "add copies", "remove moved", "remove copies")
+	for _, strategy := range STRATEGIES {
+		placements := map[string][][]string{}
+		for name, members := range map[string][]string{"before": before, "added": added, "removed": removed} {
+			placement, err := surfstore.NewPlacementStrategy(strategy, members, nil, *virtualNodes)
+			if err != nil {
+				log.Fatal(err)
+			}
+			placements[name] = placeAll(placement, blockHashes, *replication)
+		}
+
+		maxLoad, minLoad, stddev := balance(placements["before"], before)
+		addMoved, addCopies := movement(placements["before"], placements["added"])
+		removeMoved, removeCopies := movement(placements["before"], placements["removed"])
+		total := float64(len(blockHashes))
+		fmt.Printf("%-11s %8.3f %8.3f %7.1f%% %11.1f%% %12d %11.1f%% %13d\n", strategy, maxLoad, minLoad, 100*stddev,
+			100*float64(addMoved)/total, addCopies, 100*float64(removeMoved)/total, removeCopies)
+	}
+	fmt.Printf("add: %s joins, remove: %s leaves. moved: blocks whose replicas change, copies: blocks copied to a new replica\n",
+		addrs[*servers], addrs[0])
+}
+
+func readHashes(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hashes := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hash := strings.TrimSpace(scanner.Text()); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, scanner.Err()
+}
+
+func placeAll(placement surfstore.PlacementStrategy, blockHashes []string, replication int) [][]string {
+	replicas := make([][]string, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		replicas[i] = placement.GetResponsibleServers(blockHash, replication)
+	}
+	return replicas
+}
+
+// balance returns the largest and smallest load of a server relative to the average load,
+// and the standard deviation of the loads relative to the average
+func balance(replicas [][]string, servers []string) (float64, float64, float64) {
+	load := map[string]int{}
+	total := 0
+	for _, blockReplicas := range replicas {
+		for _, server := range blockReplicas {
+			load[server]++
+			total++
+		}
+	}
+	avg := float64(total) / float64(len(servers))
+	maxLoad, minLoad, squares := 0.0, math.Inf(1), 0.0
+	for _, server := range servers {
+		l := float64(load[server])
+		maxLoad = math.Max(maxLoad, l)
+		minLoad = math.Min(minLoad, l)
+		squares += (l - avg) * (l - avg)
+	}
+	return maxLoad / avg, minLoad / avg, math.Sqrt(squares/float64(len(servers))) / avg
+}
+
+// movement counts the blocks whose replicas change, and the copies it takes to fill their new replicas
+func movement(before [][]string, after [][]string) (int, int) {
+	moved, copies := 0, 0
+	for i := range before {
+		if surfstore.CompareBlockHashList(before[i], after[i]) {
+			continue
+		}
+		moved++
+		for _, server := range after[i] {
+			if !contains(before[i], server) {
+				copies++
+			}
+		}
+	}
+	return moved, copies
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 )
 
@@ -14,11 +15,14 @@ import (
 const ARG_COUNT int = 3
 
 // Usage strings
-const USAGE_STRING = "./run-client.sh -d host:port baseDir blockSize"
+const USAGE_STRING = "./run-client.sh -d [-stats] host:port baseDir blockSize"
 
 const DEBUG_NAME = "d"
 const DEBUG_USAGE = "Output log statements"
 
+const STATS_NAME = "stats"
+const STATS_USAGE = "Also print how the blocks are spread over the BlockStores"
+
 const ADDR_NAME = "host:port"
 const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to"
 
@@ -37,6 +41,7 @@ func main() {
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
 		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", STATS_NAME, STATS_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BLOCK_NAME, BLOCK_USAGE)
@@ -44,6 +49,7 @@ func main() {
 
 	// Parse command-line arguments and flags
 	debug := flag.Bool("d", false, DEBUG_USAGE)
+	stats := flag.Bool(STATS_NAME, false, STATS_USAGE)
 	flag.Parse()
 
 	// Use tail arguments to hold non-flag arguments
@@ -70,6 +76,9 @@ func main() {
 
 	rpcClient := surfstore.NewSurfstoreRPCClient(hostPort, baseDir, blockSize)
 	PrintBlocksOnEachServer(rpcClient)
+	if *stats {
+		PrintBlockDistribution(rpcClient)
+	}
 }
 
 func PrintBlocksOnEachServer(client surfstore.RPCClient) {
@@ -98,3 +107,72 @@ func PrintBlocksOnEachServer(client surfstore.RPCClient) {
 	}
 	fmt.Println(result)
 }
+
+// PrintBlockDistribution prints, for every server, its zone, the blocks of the files in the index the
+// MetaStore places on it and its share of them next to the share its weight asks for. Blocks no file
+// references any more are left out, as are the copies of a block still on a server it moved away from
+func PrintBlockDistribution(client surfstore.RPCClient) {
+	weights := map[string]int32{}
+	if err := client.GetBlockStoreWeights(&weights); err != nil {
+		log.Fatal("[Surfstore RPCClient]:", "Error During Fetching BlockStore Weights ", err)
+	}
+	zones := map[string]string{}
+	if err := client.GetBlockStoreZones(&zones); err != nil {
+		log.Fatal("[Surfstore RPCClient]:", "Error During Fetching BlockStore Zones ", err)
+	}
+	fileInfoMap := map[string]*surfstore.FileMetaData{}
+	if err := client.GetFileInfoMap(&fileInfoMap); err != nil {
+		log.Fatal("[Surfstore RPCClient]:", "Error During Fetching the File Info Map ", err)
+	}
+	liveHashes := map[string]bool{}
+	for _, fileMetaData := range fileInfoMap {
+		for _, hash := range fileMetaData.BlockHashList {
+			if hash != surfstore.TOMBSTONE_HASHVALUE && hash != surfstore.EMPTYFILE_HASHVALUE {
+				liveHashes[hash] = true
+			}
+		}
+		for _, stripe := range fileMetaData.Stripes {
+			for _, hash := range stripe.ParityHashes {
+				liveHashes[hash] = true
+			}
+		}
+	}
+	hashes := make([]string, 0, len(liveHashes))
+	for hash := range liveHashes {
+		hashes = append(hashes, hash)
+	}
+
+	counts := map[string]int{}
+	totalBlocks := 0
+	for start := 0; start < len(hashes); start += surfstore.HASH_BATCH_SIZE {
+		blockStoreMap := map[string][]string{}
+		if err := client.GetBlockStoreMap(hashes[start:min(start+surfstore.HASH_BATCH_SIZE, len(hashes))], &blockStoreMap); err != nil {
+			log.Fatal("[Surfstore RPCClient]:", "Error During Fetching the Block Store Map ", err)
+		}
+		for addr, batchHashes := range blockStoreMap {
+			counts[addr] += len(batchHashes)
+			totalBlocks += len(batchHashes)
+		}
+	}
+	allAddrs := []string{}
+	totalWeight := 0
+	for addr, weight := range weights {
+		allAddrs = append(allAddrs, addr)
+		totalWeight += int(weight)
+	}
+	sort.Strings(allAddrs)
+
+	fmt.Printf("%-24s %-8s %6s %10s %7s %8s\n", "server", "zone", "weight", "blocks", "share", "target")
+	for _, addr := range allAddrs {
+		share := 0.0
+		if totalBlocks > 0 {
+			share = 100 * float64(counts[addr]) / float64(totalBlocks)
+		}
+		target := 100 * float64(weights[addr]) / float64(totalWeight)
+		zone := zones[addr]
+		if zone == "" {
+			zone = "-"
+		}
+		fmt.Printf("%-24s %-8s %6d %10d %6.1f%% %7.1f%%\n", addr, zone, weights[addr], counts[addr], share, target)
+	}
+}
@@ -5,16 +5,21 @@ import (
 	"flag"
 	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"io"
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // Usage String
-const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d (blockStoreAddr*)"
+const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d [-storage memory|disk -dir <dataDir> -snapshot <n> -peers <metaAddr,...> -id <n> -r <n> -vnodes <n> -placement <strategy> -config <file> -meta <metaAddr,...> -weight <n> -zone <name> -repair <interval> -max-block-size <bytes> -ec <k+m> -ec-min-size <bytes> -ec-prefix <prefix,...>] (blockStoreAddr[,weight=n][,id=nodeId][,zone=name]*)"
 
 // Set of valid services
 var SERVICE_TYPES = map[string]bool{"meta": true, "block": true, "both": true}
@@ -22,6 +27,9 @@ var SERVICE_TYPES = map[string]bool{"meta": true, "block": true, "both": true}
 // Exit codes
 const EX_USAGE int = 64
 
+// How long the metastore waits before asking a blockstore that did not answer for its node ID again
+const NODE_ID_RETRY_INTERVAL time.Duration = time.Second
+
 func main() {
 	// Custom flag Usage message
 	// xxx = func() {} : anonymous function, xxx is a variable that holds a function
@@ -35,7 +43,7 @@ func main() {
 		flag.VisitAll(func(f *flag.Flag) {
 			fmt.Fprintf(w, "  -%s: %v\n", f.Name, f.Usage)
 		})
-		fmt.Fprintf(w, "  (blockStoreAddr*): BlockStore Address (include self if service type is both)\n")
+		fmt.Fprintf(w, "  (blockStoreAddr[,weight=n][,id=nodeId][,zone=name]*): BlockStore Address (include self if service type is both), a BlockStore of weight n takes n times the blocks. Blocks are placed by the node ID of the BlockStore, asked from it unless given. The replicas of a block go to distinct zones when there are enough\n")
 	}
 
 	// Parse command-line argument flags
@@ -44,6 +52,23 @@ func main() {
 	port := flag.Int("p", 8080, "(default = 8080) Port to accept connections")
 	localOnly := flag.Bool("l", false, "Only listen on localhost")
 	debug := flag.Bool("d", false, "Output log statements")
+	storage := flag.String("storage", surfstore.STORAGE_MEMORY, "(default = memory) Where the BlockStore keeps blocks: memory, disk")
+	dataDir := flag.String("dir", "", "Data directory of the server (required for -storage disk, makes the MetaStore durable)")
+	snapshotEvery := flag.Int("snapshot", surfstore.DEFAULT_SNAPSHOT_EVERY, "(default = 1000) Number of MetaStore updates between snapshots of the metadata log (not with -peers, the raft log is not compacted)")
+	peers := flag.String("peers", "", "Addresses of every MetaStore of a raft cluster, separated by commas (empty for a single MetaStore)")
+	raftId := flag.Int64("id", 0, "(default = 0) Index of this server in -peers")
+	replication := flag.Int("r", surfstore.DEFAULT_REPLICATION_FACTOR, "(default = 1) Number of BlockStores the MetaStore places each block on")
+	virtualNodes := flag.Int("vnodes", surfstore.DEFAULT_VIRTUAL_NODES, "(default = 1) Points on the consistent hash ring per unit of BlockStore weight")
+	configFile := flag.String("config", "", "File listing BlockStores like the arguments, one per line, before the ones given as arguments")
+	placement := flag.String("placement", surfstore.DEFAULT_PLACEMENT, "(default = consistent) How the MetaStore places blocks on the BlockStores: consistent, rendezvous, bounded")
+	meta := flag.String("meta", "", "(block) Addresses of the MetaStores, separated by commas, to register with on start and deregister from on SIGINT or SIGTERM")
+	weight := flag.Int("weight", 1, "(default = 1) (block, with -meta) Weight the BlockStore registers with")
+	zone := flag.String("zone", "", "(block, with -meta) Zone the BlockStore registers in")
+	repairInterval := flag.Duration("repair", surfstore.ANTI_ENTROPY_INTERVAL, "(default = 1m) (block, with -meta) Time between comparing blocks with the other replicas, 0 to never compare")
+	maxBlockSize := flag.Int("max-block-size", surfstore.DEFAULT_MAX_BLOCK_SIZE, "(default = 4194304) (block) Largest block in bytes the BlockStore stores")
+	erasureCoding := flag.String("ec", "", "(meta) Erasure code files with k data and m parity shards per stripe, as k+m, instead of replicating them (empty for no erasure coding)")
+	erasureMinSize := flag.Int64("ec-min-size", 0, "(meta, with -ec) Erasure code files of at least this many bytes (0 for no threshold)")
+	erasurePrefixes := flag.String("ec-prefix", "", "(meta, with -ec) Erasure code files whose names start with one of these prefixes, separated by commas. Without -ec-min-size and -ec-prefix every file is erasure coded")
 	flag.Parse()
 
 	// Use tail arguments to hold BlockStore address
@@ -51,11 +76,35 @@ func main() {
 	// > go run cmd/SurfstoreServerExec/main.go -s block -p 8082 -l
 	// > go run cmd/SurfstoreServerExec/main.go -s meta -l localhost:8081 localhost:8082
 
-	blockStoreAddrs := []string{}
-	for _, arg := range flag.Args() {
-		blockStoreAddrs = append(blockStoreAddrs, arg)
-		//eg: go run cmd/SurfstoreServerExec/main.go -s meta -l localhost:8081 localhost:8082
-		//blockStoreAddrs = ["localhost:8081", "localhost:8082"]
+	blockStores := &surfstore.BlockStoreAddrs{Weights: map[string]int32{}, VirtualNodes: int32(*virtualNodes), NodeIds: map[string]string{}, Placement: *placement, Zones: map[string]string{}}
+	blockStoreArgs := flag.Args()
+	if *configFile != "" {
+		fileArgs, err := readBlockStoreConfig(*configFile)
+		if err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), err)
+			os.Exit(EX_USAGE)
+		}
+		blockStoreArgs = append(fileArgs, blockStoreArgs...)
+	}
+	for _, arg := range blockStoreArgs {
+		//eg: go run cmd/SurfstoreServerExec/main.go -s meta -l localhost:8081 localhost:8082,weight=2
+		//blockStoreAddrs = ["localhost:8081", "localhost:8082"], weights = {"localhost:8082": 2}
+		blockStoreAddr, err := surfstore.ParseBlockStoreArg(arg)
+		if err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), err)
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		blockStores.BlockStoreAddrs = append(blockStores.BlockStoreAddrs, blockStoreAddr.Addr)
+		if blockStoreAddr.Weight != 1 {
+			blockStores.Weights[blockStoreAddr.Addr] = blockStoreAddr.Weight
+		}
+		if blockStoreAddr.NodeId != "" {
+			blockStores.NodeIds[blockStoreAddr.Addr] = blockStoreAddr.NodeId
+		}
+		if blockStoreAddr.Zone != "" {
+			blockStores.Zones[blockStoreAddr.Addr] = blockStoreAddr.Zone
+		}
 	}
 
 	// flag.Args(): returns the non-flag arguments, the tail arguments(blockStoreAddr*)
@@ -76,6 +125,65 @@ func main() {
 		os.Exit(EX_USAGE)
 	}
 
+	// disk storage needs somewhere to put the blocks
+	if *storage != surfstore.STORAGE_MEMORY && (*storage != surfstore.STORAGE_DISK || *dataDir == "") {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	if _, err := surfstore.NewPlacementStrategy(*placement, nil, nil, 1); err != nil || *replication < 1 || *virtualNodes < 1 || *maxBlockSize < 1 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	config := serverConfig{
+		storage:       *storage,
+		dataDir:       *dataDir,
+		snapshotEvery: *snapshotEvery,
+		raftId:        *raftId,
+		replication:   *replication,
+		weight:        int32(*weight),
+		zone:          *zone,
+		repair:        *repairInterval,
+		maxBlockSize:  *maxBlockSize,
+	}
+	if *erasureCoding != "" {
+		var err error
+		if config.erasureCoding, err = surfstore.ParseErasureCoding(*erasureCoding); err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), err)
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		if *erasureMinSize < 0 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		config.erasureCoding.MinFileSize = *erasureMinSize
+		if *erasurePrefixes != "" {
+			config.erasureCoding.Namespaces = strings.Split(*erasurePrefixes, surfstore.CONFIG_DELIMITER)
+		}
+	}
+	if *meta != "" {
+		if strings.ToLower(*service) == "meta" || *weight < 1 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		config.metaAddrs = strings.Split(*meta, surfstore.CONFIG_DELIMITER)
+	}
+	if *peers != "" {
+		config.raftPeers = strings.Split(*peers, surfstore.CONFIG_DELIMITER)
+		if *raftId < 0 || *raftId >= int64(len(config.raftPeers)) {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		// raft keeps its whole log, a snapshot interval would be silently ignored
+		if isFlagSet("snapshot") {
+			fmt.Fprintln(flag.CommandLine.Output(), "-snapshot cannot be used with -peers, the raft log is not compacted")
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+	}
+
 	// Add localhost if necessary
 	addr := ""
 	// localOnly: -l flag
@@ -91,25 +199,47 @@ func main() {
 	}
 
 	// Start the server
-	log.Fatal(startServer(addr, strings.ToLower(*service), blockStoreAddrs))
+	if err := startServer(addr, strings.ToLower(*service), blockStores, config); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// isFlagSet reports whether the flag name was given on the command line, rather than left at its default
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// serverConfig holds the optional settings of the server
+type serverConfig struct {
+	storage       string                   // where the blockstore keeps its blocks: memory, disk
+	dataDir       string                   // where the blockstore and metastore keep their data, empty for memory only
+	snapshotEvery int                      // metastore updates between snapshots of its log
+	raftPeers     []string                 // addresses of every metastore of a raft cluster, empty for a single metastore
+	raftId        int64                    // index of this server in raftPeers
+	replication   int                      // number of blockstores keeping each block
+	metaAddrs     []string                 // metastores the blockstore registers with, empty if it does not
+	weight        int32                    // weight the blockstore registers with
+	zone          string                   // zone the blockstore registers in
+	repair        time.Duration            // time between anti-entropy rounds of the blockstore, 0 for none
+	maxBlockSize  int                      // largest block the blockstore stores
+	erasureCoding *surfstore.ErasureCoding // which files clients erasure code, nil for none
 }
 
 // hostAddr: the address of the server
 // serviceType: meta, block, or both
 // blockStoreAddr: the address of the blockstore server (project 3)
-// blockStoreAddrs: a list of blockstore addresses (project 4)
-func startServer(hostAddr string, serviceType string, blockStoreAddrs []string) error {
+// blockStores: the blockstore addresses (project 4), their weights, virtual nodes and placement strategy
+// config: storage, durability and raft settings
+func startServer(hostAddr string, serviceType string, blockStores *surfstore.BlockStoreAddrs, config serverConfig) error {
 	//panic("todo")
 	grpcServer := grpc.NewServer()
 
-	// register the server to the grpc server (have get the lower case of the service type)
-	if serviceType == "meta" || serviceType == "both" {
-		surfstore.RegisterMetaStoreServer(grpcServer, surfstore.NewMetaStore(blockStoreAddrs))
-	}
-	if serviceType == "block" || serviceType == "both" {
-		surfstore.RegisterBlockStoreServer(grpcServer, surfstore.NewBlockStore())
-	}
-
 	// listen to the hostAddr
 	listener, err := net.Listen("tcp", hostAddr)
 	fmt.Println("Started listening")
@@ -117,6 +247,80 @@ func startServer(hostAddr string, serviceType string, blockStoreAddrs []string)
 		return err
 	}
 
+	// with service type both, the addresses the ring may have for the blockstore served here
+	local := map[string]string{}
+	var blockStore *surfstore.BlockStore
+	if serviceType == "block" || serviceType == "both" {
+		// a blockstore in memory is named after its address, so it is the same node after a restart
+		blockStore = surfstore.NewBlockStoreAt(advertisedAddr(listener))
+		if config.storage == surfstore.STORAGE_DISK {
+			if blockStore, err = surfstore.NewDiskBlockStore(config.dataDir); err != nil {
+				return err
+			}
+		}
+		blockStore.MaxBlockSize = config.maxBlockSize
+		surfstore.RegisterBlockStoreServer(grpcServer, blockStore)
+		fmt.Println("Node ID:", blockStore.NodeId)
+		for _, addr := range []string{hostAddr, listener.Addr().String(), advertisedAddr(listener)} {
+			local[addr] = blockStore.NodeId
+		}
+	}
+
+	// register the server to the grpc server (have get the lower case of the service type)
+	if serviceType == "meta" || serviceType == "both" {
+		if len(config.raftPeers) > 0 {
+			// the raft log makes the metastore durable, the metastore itself is rebuilt from it
+			transport := surfstore.NewGrpcRaftTransport(config.raftPeers)
+			metaStore := surfstore.NewWeightedMetaStore(blockStores)
+			metaStore.ReplicationFactor = config.replication
+			metaStore.ErasureCoding = config.erasureCoding
+			metaStore.StartHealthChecks()
+			raftServer, err := surfstore.NewRaftSurfstore(config.raftId, int64(len(config.raftPeers)), metaStore, transport, config.dataDir)
+			if err != nil {
+				return err
+			}
+			// the first leader puts its ring in the raft log, after that the log decides for every server
+			if !raftServer.HasBlockStores() {
+				if err := resolveNodeIds(metaStore, blockStores, local); err != nil {
+					return err
+				}
+			}
+			raftServer.Start()
+			surfstore.RegisterMetaStoreServer(grpcServer, raftServer)
+			surfstore.RegisterRaftSurfstoreServer(grpcServer, raftServer)
+		} else {
+			metaStore := surfstore.NewWeightedMetaStore(blockStores)
+			if config.dataDir != "" {
+				var err error
+				if metaStore, err = surfstore.NewPersistentMetaStore(blockStores, config.dataDir, config.snapshotEvery); err != nil {
+					return err
+				}
+			}
+			// a saved ring has its node IDs, the one from the command line is saved with them
+			saved := false
+			if metaStore.Log != nil {
+				savedBlockStores, err := metaStore.Log.BlockStores()
+				if err != nil {
+					return err
+				}
+				saved = savedBlockStores != nil
+			}
+			if !saved {
+				if err := resolveNodeIds(metaStore, blockStores, local); err != nil {
+					return err
+				}
+			}
+			metaStore.ReplicationFactor = config.replication
+			metaStore.ErasureCoding = config.erasureCoding
+			metaStore.StartHealthChecks()
+			surfstore.RegisterMetaStoreServer(grpcServer, metaStore)
+		}
+	}
+	// the metastore asks the blockstore for its node ID, so it has to be listening before it registers
+	if len(config.metaAddrs) > 0 && (serviceType == "block" || serviceType == "both") {
+		go registerBlockStore(grpcServer, blockStore, advertisedAddr(listener), config)
+	}
+
 	// serve the grpc server
 	// grpc handles the incoming requests using the listener
 	err = grpcServer.Serve(listener)
@@ -125,3 +329,118 @@ func startServer(hostAddr string, serviceType string, blockStoreAddrs []string)
 	}
 	return nil
 }
+
+// advertisedAddr is the address the blockstore registers: localhost with -l, the host name otherwise
+func advertisedAddr(listener net.Listener) string {
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		if hostname, err := os.Hostname(); err == nil {
+			host = hostname
+		}
+	} else {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// registerBlockStore puts the blockstore on the ring, retrying while no metastore answers, then starts
+// anti-entropy. It takes the blockstore off the ring again on SIGINT or SIGTERM, which moves its blocks
+// to the other blockstores before the server stops
+func registerBlockStore(grpcServer *grpc.Server, blockStore *surfstore.BlockStore, blockStoreAddr string, config serverConfig) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	client := surfstore.NewAdminRPCClient(strings.Join(config.metaAddrs, surfstore.CONFIG_DELIMITER))
+	blockStore.SetMetaStore(client)
+	report := &surfstore.MigrationReport{}
+	for {
+		err := client.RegisterBlockStore(blockStoreAddr, config.weight, config.zone, report)
+		if err == nil {
+			fmt.Printf("Registered as %s, %d blocks moved here\n", blockStoreAddr, report.BlocksCopied)
+			break
+		}
+		if code := status.Code(err); code != codes.Unavailable && code != codes.DeadlineExceeded {
+			fmt.Fprintln(os.Stderr, "Cannot register with the MetaStore:", err)
+			signal.Stop(signals)
+			return
+		}
+		log.Printf("No MetaStore answers, registering again in %v: %v", surfstore.HEALTH_CHECK_INTERVAL, err)
+		select {
+		case <-signals:
+			grpcServer.Stop()
+			return
+		case <-time.After(surfstore.HEALTH_CHECK_INTERVAL):
+		}
+	}
+
+	if config.repair > 0 {
+		blockStore.StartAntiEntropy(client, config.repair)
+	}
+
+	<-signals
+	fmt.Println("Deregistering, moving the blocks to the other BlockStores")
+	if err := client.RemoveBlockStore(blockStoreAddr, report); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot deregister from the MetaStore:", err)
+	} else {
+		fmt.Printf("Deregistered, %d blocks moved\n", report.BlocksCopied)
+	}
+	grpcServer.GracefulStop()
+}
+
+// readBlockStoreConfig reads the BlockStores of a -config file, one per line written like an argument:
+//
+//	# zone a
+//	localhost:8081,zone=a
+//	localhost:8082,weight=2,zone=a
+//
+// Blank lines and lines starting with # are skipped
+func readBlockStoreConfig(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args = append(args, line)
+	}
+	return args, nil
+}
+
+// resolveNodeIds asks every BlockStore without a node ID on the command line for its node ID, and
+// keeps asking the ones that do not answer yet: a BlockStore placed by its address until then would
+// have its blocks placed elsewhere once its node ID is known. The ring of metaStore is then replaced.
+// local has the addresses of the BlockStore served here with service type both, which is not
+// serving yet and is not asked: its node ID is known
+func resolveNodeIds(metaStore *surfstore.MetaStore, blockStores *surfstore.BlockStoreAddrs, local map[string]string) error {
+	client := &surfstore.RPCClient{}
+	pending := make([]string, 0, len(blockStores.BlockStoreAddrs))
+	for _, blockStoreAddr := range blockStores.BlockStoreAddrs {
+		if _, ok := blockStores.NodeIds[blockStoreAddr]; ok {
+			continue
+		}
+		if nodeId, ok := local[blockStoreAddr]; ok {
+			blockStores.NodeIds[blockStoreAddr] = nodeId
+			continue
+		}
+		pending = append(pending, blockStoreAddr)
+	}
+	for len(pending) > 0 {
+		waiting := make([]string, 0, len(pending))
+		for _, blockStoreAddr := range pending {
+			nodeId := ""
+			if err := client.GetNodeId(blockStoreAddr, &nodeId); err != nil {
+				fmt.Printf("Waiting for the node ID of %s: %v\n", blockStoreAddr, err)
+				waiting = append(waiting, blockStoreAddr)
+				continue
+			}
+			blockStores.NodeIds[blockStoreAddr] = nodeId
+		}
+		if pending = waiting; len(pending) > 0 {
+			time.Sleep(NODE_ID_RETRY_INTERVAL)
+		}
+	}
+	return metaStore.SetBlockStores(blockStores)
+}
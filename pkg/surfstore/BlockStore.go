@@ -1,81 +1,261 @@
 package surfstore
 
-import (
-	"google.golang.org/protobuf/types/known/emptypb"
-	"sync"
-)
-
-// don't know the reason but when use defer bs.RWMutex.RUnlock():
-// it will -> fatal error: sync: RUnlock of unlocked RWMutex
-
 import (
 	context "context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type BlockStore struct {
-	// BlockMap is a map that stores the block hash as the key and the block as the value
-	BlockMap map[string]*Block
-	RWMutex  sync.RWMutex
+	// Storage keeps the blocks, either in memory (MemoryBlockStorage) or on disk (DiskBlockStorage)
+	Storage BlockStorage
+	// NodeId names this server on the ring whatever its address, kept in the data directory,
+	// or derived from the address for a server keeping its blocks in memory
+	NodeId string
+	// MaxBlockSize is the largest block PutBlock takes, in bytes
+	MaxBlockSize int
+	// repair is the progress of anti-entropy, see StartAntiEntropy
+	repair repairProgress
 	UnimplementedBlockStoreServer
 }
 
 func (bs *BlockStore) GetBlock(ctx context.Context, blockHash *BlockHash) (*Block, error) {
 	// hash -> block
-	// RWMutex: when multiple clients try to get the block, map could be modified
-	bs.RWMutex.RLock()
-	//defer bs.RWMutex.RUnlock()
-	block := bs.BlockMap[blockHash.Hash]
-	bs.RWMutex.RUnlock()
+	block, exists, err := bs.Storage.Get(blockHash.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "block %s not found", blockHash.Hash)
+	}
 	return block, nil
 }
 
 // Return a list containing all blockHashes on this block server
 func (bs *BlockStore) GetBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error) {
-	//panic("todo")
-	hashes := make([]string, 0)
-	bs.RWMutex.RLock()
-	for hash := range bs.BlockMap { // hash: key(block hash)
-		hashes = append(hashes, hash)
+	hashes, err := bs.Storage.Hashes()
+	if err != nil {
+		return nil, err
 	}
-	bs.RWMutex.RUnlock()
 	return &BlockHashes{Hashes: hashes}, nil
 }
 
 func (bs *BlockStore) PutBlock(ctx context.Context, block *Block) (*Success, error) {
-	// block -> hash, then add to the map
-	hash := GetBlockHashString(block.BlockData)
-	//fmt.Println("hash: ", hash)
-	//fmt.Println("block size: ", block.BlockSize)
-	//fmt.Println("block content: ", block.BlockData)
-	bs.RWMutex.Lock()
-	//defer bs.RWMutex.RUnlock()
-	bs.BlockMap[hash] = block
-	bs.RWMutex.Unlock()
+	// block -> hash, then add to the storage
+	hash, err := bs.validateBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	if err := bs.Storage.Put(hash, block); err != nil {
+		return nil, err
+	}
 	return &Success{Flag: true}, nil
 }
 
+// validateBlock checks a block before it is stored and returns its hash: a client must not get
+// a block stored that another client would read back wrong
+func (bs *BlockStore) validateBlock(block *Block) (string, error) {
+	if bs.MaxBlockSize > 0 && len(block.BlockData) > bs.MaxBlockSize {
+		return "", status.Errorf(codes.ResourceExhausted, "block of %d bytes is larger than %d bytes", len(block.BlockData), bs.MaxBlockSize)
+	}
+	if int(block.BlockSize) != len(block.BlockData) {
+		return "", status.Errorf(codes.InvalidArgument, "block size %d does not match its %d bytes", block.BlockSize, len(block.BlockData))
+	}
+	hash := GetBlockHashString(block.BlockData)
+	if block.Hash != "" && block.Hash != hash {
+		return "", status.Errorf(codes.InvalidArgument, "block hashes to %s, not %s", hash, block.Hash)
+	}
+	return hash, nil
+}
+
 // Given a list of hashes “in”, returns a list containing the
-// hashes that are not stored in the key-value store
+// hashes that are not stored in the key-value store.
+// A client skips uploading the blocks we already have, so those are touched: an unreferenced
+// block about to be referenced again must not be swept before the client calls UpdateFile
 func (bs *BlockStore) MissingBlocks(ctx context.Context, blockHashesIn *BlockHashes) (*BlockHashes, error) {
 	hashNoStore := make([]string, 0)
-	//bs.RWMutex.RLock()
-	//defer bs.RWMutex.RUnlock()
 	for _, blockHash := range blockHashesIn.Hashes {
-		bs.RWMutex.RLock()
-		_, exists := bs.BlockMap[blockHash]
-		bs.RWMutex.RUnlock()
+		exists, err := bs.Storage.Has(blockHash)
+		if err != nil {
+			return nil, err
+		}
 		if !exists {
 			hashNoStore = append(hashNoStore, blockHash)
+		} else if err := bs.Storage.Touch(blockHash); err != nil {
+			return nil, err
 		}
 	}
 	return &BlockHashes{Hashes: hashNoStore}, nil
 }
 
+// Store every block sent on the stream, one at a time
+func (bs *BlockStore) PutBlocks(stream BlockStore_PutBlocksServer) error {
+	for {
+		block, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&Success{Flag: true})
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := bs.PutBlock(stream.Context(), block); err != nil {
+			return err
+		}
+	}
+}
+
+// Send the blocks of the given hashes, in the same order. Send blocks while the
+// client is behind, so only a window of blocks is ever in flight
+func (bs *BlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	for _, hash := range blockHashesIn.Hashes {
+		block, exists, err := bs.Storage.Get(hash)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return status.Errorf(codes.NotFound, "block %s not found", hash)
+		}
+		if err := stream.Send(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive the live hashes, then delete every stored block that is not live.
+// Blocks written (or touched) within the grace period are kept, they may belong
+// to an upload whose UpdateFile has not reached the MetaStore yet
+func (bs *BlockStore) SweepBlocks(stream BlockStore_SweepBlocksServer) error {
+	live := make(map[string]bool)
+	gracePeriod := time.Duration(0)
+	for {
+		liveBlocks, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, hash := range liveBlocks.Hashes {
+			live[hash] = true
+		}
+		gracePeriod = time.Duration(liveBlocks.GracePeriodSeconds) * time.Second
+	}
+
+	hashes, err := bs.Storage.Hashes()
+	if err != nil {
+		return err
+	}
+	report := &GarbageCollectionReport{}
+	cutoff := time.Now().Add(-gracePeriod)
+	for _, hash := range hashes {
+		report.BlocksScanned++
+		if live[hash] {
+			continue
+		}
+		info, exists, err := bs.Storage.Stat(hash)
+		if err != nil {
+			return err
+		}
+		if !exists || info.ModTime.After(cutoff) {
+			continue
+		}
+		if err := bs.Storage.Delete(hash); err != nil {
+			return err
+		}
+		report.BlocksDeleted++
+		report.BytesReclaimed += info.Size
+	}
+	return stream.SendAndClose(report)
+}
+
+// Return the node ID the MetaStore places blocks on this server by
+func (bs *BlockStore) GetNodeId(ctx context.Context, _ *emptypb.Empty) (*NodeId, error) {
+	return &NodeId{Id: bs.NodeId}, nil
+}
+
 // This line guarantees all method for BlockStore are implemented
 var _ BlockStoreInterface = new(BlockStore)
 
+// NewBlockStore creates a BlockStore that keeps every block in memory.
+// Its node ID is new every time, like its blocks
 func NewBlockStore() *BlockStore {
 	return &BlockStore{
-		BlockMap: map[string]*Block{},
+		Storage:      NewMemoryBlockStorage(),
+		NodeId:       newNodeId(),
+		MaxBlockSize: DEFAULT_MAX_BLOCK_SIZE,
+	}
+}
+
+// NewBlockStoreAt creates a BlockStore that keeps every block in memory, serving at addr.
+// Its node ID comes from addr, so it keeps its place on the ring across restarts, only its blocks are lost
+func NewBlockStoreAt(addr string) *BlockStore {
+	blockStore := NewBlockStore()
+	blockStore.NodeId = addrNodeId(addr)
+	return blockStore
+}
+
+// NewDiskBlockStore creates a BlockStore that keeps every block under dataDir,
+// so the blocks survive a restart of the server
+func NewDiskBlockStore(dataDir string) (*BlockStore, error) {
+	storage, err := NewDiskBlockStorage(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	nodeId, err := loadNodeId(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStore{
+		Storage:      storage,
+		NodeId:       nodeId,
+		MaxBlockSize: DEFAULT_MAX_BLOCK_SIZE,
+	}, nil
+}
+
+const nodeIdFilename string = "node_id"
+
+// loadNodeId reads the node ID kept in dataDir, generating it the first time
+func loadNodeId(dataDir string) (string, error) {
+	path := filepath.Join(dataDir, nodeIdFilename)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	nodeId := newNodeId()
+	if err := writeFileAtomic(path, []byte(nodeId+"\n")); err != nil {
+		return "", err
+	}
+	return nodeId, nil
+}
+
+// addrNodeId returns 128 bits of the hash of addr in hex, the same for the same address every time
+func addrNodeId(addr string) string {
+	sum := sha256.Sum256([]byte("node/" + addr))
+	return hex.EncodeToString(sum[:16])
+}
+
+// newNodeId returns 128 random bits in hex
+func newNodeId() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		log.Fatalf("Cannot generate a node ID: %v", err)
 	}
+	return hex.EncodeToString(id)
 }
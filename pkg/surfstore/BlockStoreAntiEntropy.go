@@ -0,0 +1,377 @@
+package surfstore
+
+import (
+	"bytes"
+	context "context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Anti-entropy: every so often a block server gets the ring from the MetaStore and, with every other
+// block server on the ring, compares a Merkle tree of the blocks both of them should hold. Which
+// servers should hold a block comes from the MetaStore's GetBlockStoreMap, since the shards of an
+// erasure coded stripe are placed by their stripe and not by their own hash.
+// The hash space is cut into ranges by the first merkleRangeDigits hex digits of a hash. A leaf of the
+// tree is the digest of the hashes in one range and every other node the digest of its two children,
+// so equal roots end the comparison right away, and otherwise only the ranges that differ get listed.
+// Blocks of those ranges the peer has and we do not are copied with GetBlock and PutBlock. Every block
+// server pulls what it lacks, so the replicas agree again once each of them has run a round.
+
+// 256 ranges
+const merkleRangeDigits = 2
+const merkleRanges = 1 << (4 * merkleRangeDigits)
+
+// repairProgress is what GetRepairStatus reports
+type repairProgress struct {
+	mu                  sync.Mutex
+	enabled             bool
+	meta                *RPCClient // places the blocks we hold, for us and for the peers asking
+	running             bool
+	roundStarted        time.Time
+	lastRepair          time.Time // end of the last round that compared every peer
+	peersDone           int
+	peers               int
+	rangesDiffering     int64
+	blocksRepaired      int64 // in the current or last round
+	blocksRepairedTotal int64
+	err                 string
+}
+
+// StartAntiEntropy runs a round of anti-entropy every interval, with the ring of the MetaStore
+// meta talks to
+func (bs *BlockStore) StartAntiEntropy(meta RPCClient, interval time.Duration) {
+	bs.SetMetaStore(meta)
+	bs.repair.mu.Lock()
+	bs.repair.enabled = true
+	bs.repair.mu.Unlock()
+	go func() {
+		for {
+			if err := bs.repairRound(meta); err != nil {
+				log.Printf("Anti-entropy round failed: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// SetMetaStore sets the MetaStore that tells which servers should hold our blocks, which peers
+// comparing their blocks with ours need even when we run no anti-entropy ourselves
+func (bs *BlockStore) SetMetaStore(meta RPCClient) {
+	bs.repair.mu.Lock()
+	defer bs.repair.mu.Unlock()
+	bs.repair.meta = &meta
+}
+
+func (bs *BlockStore) repairRound(meta RPCClient) error {
+	blockStores := &BlockStoreAddrs{}
+	if err := meta.GetBlockStoreRing(blockStores); err != nil {
+		return bs.endRound(err)
+	}
+	shared, err := bs.sharedHashes(blockStores, "")
+	if err != nil {
+		return bs.endRound(err)
+	}
+	// every other server, a peer we share no block with may have the ones we lost
+	selfAddr, _ := bs.selfAddr(blockStores)
+	peers := make([]string, 0, len(blockStores.BlockStoreAddrs))
+	for _, addr := range blockStores.BlockStoreAddrs {
+		if addr != selfAddr {
+			peers = append(peers, addr)
+		}
+	}
+	sort.Strings(peers)
+
+	bs.repair.mu.Lock()
+	bs.repair.running = true
+	bs.repair.roundStarted = time.Now()
+	bs.repair.peersDone, bs.repair.peers = 0, len(peers)
+	bs.repair.rangesDiffering, bs.repair.blocksRepaired = 0, 0
+	bs.repair.mu.Unlock()
+
+	client := &RPCClient{}
+	failed := make([]string, 0)
+	for _, peer := range peers {
+		if err := bs.repairFrom(client, blockStores, peer, shared[peer]); err != nil {
+			log.Printf("Anti-entropy with %s failed: %v", peer, err)
+			failed = append(failed, peer)
+		}
+		bs.repair.mu.Lock()
+		bs.repair.peersDone++
+		bs.repair.mu.Unlock()
+	}
+	if len(failed) > 0 {
+		return bs.endRound(fmt.Errorf("cannot compare with %s", strings.Join(failed, CONFIG_DELIMITER)))
+	}
+	return bs.endRound(nil)
+}
+
+func (bs *BlockStore) endRound(err error) error {
+	bs.repair.mu.Lock()
+	defer bs.repair.mu.Unlock()
+	bs.repair.running = false
+	if err != nil {
+		bs.repair.err = err.Error()
+		return err
+	}
+	bs.repair.err = ""
+	bs.repair.lastRepair = time.Now()
+	return nil
+}
+
+// repairFrom compares the blocks shared with peer and copies the ones we are missing
+func (bs *BlockStore) repairFrom(client *RPCClient, blockStores *BlockStoreAddrs, peer string, ours []string) error {
+	selfAddr, err := bs.selfAddr(blockStores)
+	if err != nil {
+		return err
+	}
+	request := &MerkleTreeRequest{BlockStores: blockStores, Peer: selfAddr}
+	theirTree := [][]byte{}
+	if err := client.GetMerkleTree(peer, request, &theirTree); err != nil {
+		return err
+	}
+	ranges := diffMerkleTrees(buildMerkleTree(ours), theirTree)
+	if len(ranges) == 0 {
+		return nil
+	}
+	bs.repair.mu.Lock()
+	bs.repair.rangesDiffering += int64(len(ranges))
+	bs.repair.mu.Unlock()
+
+	request.Ranges = ranges
+	theirs := []string{}
+	if err := client.GetRangeHashes(peer, request, &theirs); err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(ours))
+	for _, hash := range ours {
+		have[hash] = true
+	}
+	for _, hash := range theirs {
+		if have[hash] {
+			continue
+		}
+		block := &Block{}
+		if err := client.GetBlock(hash, peer, block); err != nil {
+			if status.Code(err) == codes.NotFound {
+				// gone from the peer since it listed it
+				continue
+			}
+			return err
+		}
+		if GetBlockHashString(block.BlockData) != hash {
+			log.Printf("Anti-entropy: %s sent a block that does not match %s, skipping it", peer, hash)
+			continue
+		}
+		block.Hash = hash
+		if _, err := bs.PutBlock(context.Background(), block); err != nil {
+			return err
+		}
+		bs.repair.mu.Lock()
+		bs.repair.blocksRepaired++
+		bs.repair.blocksRepairedTotal++
+		bs.repair.mu.Unlock()
+	}
+	return nil
+}
+
+// selfAddr is our address on the ring, found by node ID
+func (bs *BlockStore) selfAddr(blockStores *BlockStoreAddrs) (string, error) {
+	for _, addr := range blockStores.BlockStoreAddrs {
+		if blockStoreNodeId(blockStores, addr) == bs.NodeId {
+			return addr, nil
+		}
+	}
+	return "", status.Errorf(codes.FailedPrecondition, "node ID %s is not on the ring", bs.NodeId)
+}
+
+// sharedHashes sorts the blocks we hold and should hold by the other servers that should hold them
+// too. With a peer, only the blocks shared with that peer are listed. A shard only one server holds
+// is shared with nobody, so it is never compared
+func (bs *BlockStore) sharedHashes(blockStores *BlockStoreAddrs, peer string) (map[string][]string, error) {
+	selfAddr, err := bs.selfAddr(blockStores)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := bs.Storage.Hashes()
+	if err != nil {
+		return nil, err
+	}
+	servers, err := bs.blockServers(hashes)
+	if err != nil {
+		return nil, err
+	}
+	shared := map[string][]string{}
+	for _, hash := range hashes {
+		replicas := servers[hash]
+		if !containsString(replicas, selfAddr) {
+			continue
+		}
+		for _, replica := range replicas {
+			if replica != selfAddr && (peer == "" || replica == peer) {
+				shared[replica] = append(shared[replica], hash)
+			}
+		}
+	}
+	return shared, nil
+}
+
+// blockServers asks the MetaStore which servers should hold each of hashes
+func (bs *BlockStore) blockServers(hashes []string) (map[string][]string, error) {
+	bs.repair.mu.Lock()
+	meta := bs.repair.meta
+	bs.repair.mu.Unlock()
+	if meta == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no MetaStore to place the blocks, the block server was started without -meta")
+	}
+	servers := make(map[string][]string, len(hashes))
+	for start := 0; start < len(hashes); start += HASH_BATCH_SIZE {
+		blockStoreMap := map[string][]string{}
+		if err := meta.GetBlockStoreMap(hashes[start:min(start+HASH_BATCH_SIZE, len(hashes))], &blockStoreMap); err != nil {
+			return nil, err
+		}
+		for server, hashes := range blockStoreMap {
+			for _, hash := range hashes {
+				servers[hash] = append(servers[hash], server)
+			}
+		}
+	}
+	return servers, nil
+}
+
+// Answers a peer comparing the blocks it shares with us
+func (bs *BlockStore) GetMerkleTree(ctx context.Context, request *MerkleTreeRequest) (*MerkleTree, error) {
+	shared, err := bs.sharedHashes(request.BlockStores, request.Peer)
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleTree{Nodes: buildMerkleTree(shared[request.Peer])}, nil
+}
+
+// Lists the blocks we share with a peer in the ranges its Merkle tree differs from ours.
+// Without a peer, every block we hold in the ranges is listed, which is how a migration finds the
+// blocks of the ranges that move
+func (bs *BlockStore) GetRangeHashes(ctx context.Context, request *MerkleTreeRequest) (*BlockHashes, error) {
+	if request.Peer == "" {
+		hashes := make([]string, 0)
+		for _, r := range request.Ranges {
+			inRange, err := bs.Storage.HashesWithPrefix(fmt.Sprintf("%0*x", merkleRangeDigits, r))
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, inRange...)
+		}
+		return &BlockHashes{Hashes: hashes}, nil
+	}
+	shared, err := bs.sharedHashes(request.BlockStores, request.Peer)
+	if err != nil {
+		return nil, err
+	}
+	ranges := map[int]bool{}
+	for _, r := range request.Ranges {
+		ranges[int(r)] = true
+	}
+	hashes := make([]string, 0)
+	for _, hash := range shared[request.Peer] {
+		if ranges[merkleRange(hash)] {
+			hashes = append(hashes, hash)
+		}
+	}
+	return &BlockHashes{Hashes: hashes}, nil
+}
+
+func (bs *BlockStore) GetRepairStatus(ctx context.Context, _ *emptypb.Empty) (*RepairStatus, error) {
+	bs.repair.mu.Lock()
+	defer bs.repair.mu.Unlock()
+	repairStatus := &RepairStatus{
+		Enabled:             bs.repair.enabled,
+		Running:             bs.repair.running,
+		PeersDone:           int32(bs.repair.peersDone),
+		Peers:               int32(bs.repair.peers),
+		RangesDiffering:     bs.repair.rangesDiffering,
+		BlocksRepaired:      bs.repair.blocksRepaired,
+		BlocksRepairedTotal: bs.repair.blocksRepairedTotal,
+		Error:               bs.repair.err,
+	}
+	if !bs.repair.roundStarted.IsZero() {
+		repairStatus.RoundStarted = bs.repair.roundStarted.UnixMilli()
+	}
+	if !bs.repair.lastRepair.IsZero() {
+		repairStatus.LastRepair = bs.repair.lastRepair.UnixMilli()
+	}
+	return repairStatus, nil
+}
+
+// merkleRange is the range of a hash, hashes that are not hex all go to range 0
+func merkleRange(hash string) int {
+	if len(hash) < merkleRangeDigits {
+		return 0
+	}
+	r, err := strconv.ParseUint(hash[:merkleRangeDigits], 16, 64)
+	if err != nil {
+		return 0
+	}
+	return int(r)
+}
+
+// buildMerkleTree returns the nodes of the tree of hashes as a heap: the root at 1, the children of
+// node i at 2i and 2i+1, and the leaf of range r at merkleRanges+r. An empty range has an empty digest
+func buildMerkleTree(hashes []string) [][]byte {
+	leaves := make([][]string, merkleRanges)
+	for _, hash := range hashes {
+		r := merkleRange(hash)
+		leaves[r] = append(leaves[r], hash)
+	}
+	nodes := make([][]byte, 2*merkleRanges)
+	for r, leaf := range leaves {
+		if len(leaf) == 0 {
+			nodes[merkleRanges+r] = []byte{}
+			continue
+		}
+		sort.Strings(leaf)
+		digest := sha256.Sum256([]byte(strings.Join(leaf, HASH_DELIMITER)))
+		nodes[merkleRanges+r] = digest[:]
+	}
+	for i := merkleRanges - 1; i >= 1; i-- {
+		digest := sha256.Sum256(append(append([]byte{}, nodes[2*i]...), nodes[2*i+1]...))
+		nodes[i] = digest[:]
+	}
+	nodes[0] = []byte{}
+	return nodes
+}
+
+// diffMerkleTrees walks down from the root into the subtrees that differ, and returns their ranges
+func diffMerkleTrees(ours [][]byte, theirs [][]byte) []int32 {
+	if len(theirs) != len(ours) {
+		// a peer cutting the hash space another way, compare every range
+		ranges := make([]int32, merkleRanges)
+		for r := range ranges {
+			ranges[r] = int32(r)
+		}
+		return ranges
+	}
+	ranges := make([]int32, 0)
+	var walk func(i int)
+	walk = func(i int) {
+		if bytes.Equal(ours[i], theirs[i]) {
+			return
+		}
+		if i >= merkleRanges {
+			ranges = append(ranges, int32(i-merkleRanges))
+			return
+		}
+		walk(2 * i)
+		walk(2*i + 1)
+	}
+	walk(1)
+	return ranges
+}
@@ -0,0 +1,79 @@
+package surfstore
+
+import (
+	context "context"
+	"sort"
+	"testing"
+)
+
+func TestAntiEntropyLeavesErasureCodedShardsOnTheirStripeServers(t *testing.T) {
+	blockStores := startTestBlockStores(t, 4)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ReplicationFactor = 2
+	metaStore.ErasureCoding = &ErasureCoding{DataShards: 2, ParityShards: 1, MinFileSize: 8 * 1024}
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{"coded.bin": 24 * 1024, "replicated.bin": 4 * 1024})
+	ClientSync(uploader)
+
+	// the node IDs of the test servers are not on the ring, their addresses are
+	byAddr := map[string]*BlockStore{}
+	for _, blockStore := range blockStores {
+		blockStore.BlockStore.NodeId = blockStore.Addr
+		blockStore.BlockStore.SetMetaStore(NewAdminRPCClient(metaAddr))
+		byAddr[blockStore.Addr] = blockStore.BlockStore
+	}
+	// a replica loses a replicated block, which anti-entropy brings back
+	ring := metaStore.blockStores()
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := uploader.GetFileInfoMap(&fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	lost := fileInfoMap["replicated.bin"].BlockHashList[0]
+	lostFrom := metaStore.blockServers(ring, lost)[1]
+	if err := byAddr[lostFrom].Storage.Delete(lost); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := NewAdminRPCClient(metaAddr)
+	repairAll := func() int64 {
+		repaired := int64(0)
+		for _, blockStore := range blockStores {
+			if err := blockStore.BlockStore.repairRound(meta); err != nil {
+				t.Fatal(err)
+			}
+			repaired += blockStore.BlockStore.repair.blocksRepaired
+		}
+		return repaired
+	}
+	if repaired := repairAll(); repaired != 1 {
+		t.Fatalf("anti-entropy copied %d blocks, one was lost", repaired)
+	}
+	if _, err := metaStore.CollectGarbage(context.Background(), &GarbageCollection{}); err != nil {
+		t.Fatal(err)
+	}
+	// with every copy where the MetaStore places it, another round has nothing to copy
+	if repaired := repairAll(); repaired != 0 {
+		t.Fatalf("anti-entropy copied %d blocks that garbage collection had just swept", repaired)
+	}
+
+	for _, hash := range metaStore.liveHashes() {
+		want := metaStore.blockServers(ring, hash)
+		holders := []string{}
+		for addr, blockStore := range byAddr {
+			if has, _ := blockStore.Storage.Has(hash); has {
+				holders = append(holders, addr)
+			}
+		}
+		sort.Strings(want)
+		sort.Strings(holders)
+		if !CompareBlockHashList(holders, want) {
+			t.Fatalf("block %s is on %v, it belongs on %v", hash, holders, want)
+		}
+	}
+
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+}
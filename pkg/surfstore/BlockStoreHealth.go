@@ -0,0 +1,170 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// The MetaStore probes every block server on the ring with GetNodeId every HEALTH_CHECK_INTERVAL.
+// A block server that has not answered for BLOCKSTORE_SUSPECT_TIMEOUT is suspect, and down after
+// BLOCKSTORE_DOWN_TIMEOUT. GetBlockStoreMap leaves out the replicas that are down as long as a block
+// has another replica, so clients read from and write to the live ones. A block server answering
+// with another node ID than the ring has for it counts as not answering.
+
+type blockStoreHealth struct {
+	mu sync.Mutex
+	// address -> what the probes found out
+	records map[string]*healthRecord
+	// the clock the timeouts are measured with
+	now func() time.Time
+}
+
+func newBlockStoreHealth() *blockStoreHealth {
+	return &blockStoreHealth{records: map[string]*healthRecord{}, now: time.Now}
+}
+
+type healthRecord struct {
+	// last answer, or when probing started if there was none yet
+	lastSeen time.Time
+	answered bool
+	err      string
+	// last status logged
+	status string
+}
+
+// StartHealthChecks starts probing the block servers. Until then their status is unknown,
+// and GetBlockStoreMap leaves none of them out
+func (m *MetaStore) StartHealthChecks() {
+	health := newBlockStoreHealth()
+	m.health.Store(health)
+	go func() {
+		for {
+			health.probe(m.blockStores().config)
+			time.Sleep(HEALTH_CHECK_INTERVAL)
+		}
+	}()
+}
+
+// probe asks every block server for its node ID, all at once so one hanging server delays no other
+func (h *blockStoreHealth) probe(blockStores *BlockStoreAddrs) {
+	client := &RPCClient{}
+	var wg sync.WaitGroup
+	for _, addr := range blockStores.BlockStoreAddrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			nodeId := ""
+			err := client.GetNodeId(addr, &nodeId)
+			if expected := blockStoreNodeId(blockStores, addr); err == nil && expected != addr && nodeId != expected {
+				err = fmt.Errorf("answers with node ID %s, not %s", nodeId, expected)
+			}
+			h.record(addr, err)
+		}(addr)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	for addr, record := range h.records {
+		if !containsString(blockStores.BlockStoreAddrs, addr) {
+			delete(h.records, addr)
+			continue
+		}
+		if status := record.statusAt(now); status != record.status {
+			if record.err != "" {
+				log.Printf("Block store %s is %s: %s", addr, status, record.err)
+			} else {
+				log.Printf("Block store %s is %s", addr, status)
+			}
+			record.status = status
+		}
+	}
+}
+
+func (h *blockStoreHealth) record(addr string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	record, ok := h.records[addr]
+	if !ok {
+		// a new server gets the timeouts to answer
+		record = &healthRecord{lastSeen: h.now(), status: BLOCKSTORE_UP}
+		h.records[addr] = record
+	}
+	if err != nil {
+		record.err = err.Error()
+		return
+	}
+	record.lastSeen = h.now()
+	record.answered = true
+	record.err = ""
+}
+
+func (r *healthRecord) statusAt(now time.Time) string {
+	switch since := now.Sub(r.lastSeen); {
+	case since >= BLOCKSTORE_DOWN_TIMEOUT:
+		return BLOCKSTORE_DOWN
+	case since >= BLOCKSTORE_SUSPECT_TIMEOUT:
+		return BLOCKSTORE_SUSPECT
+	default:
+		return BLOCKSTORE_UP
+	}
+}
+
+// status returns the status of a block server, and the record of the probes if there is one
+func (h *blockStoreHealth) status(addr string) (string, healthRecord) {
+	if h == nil {
+		return BLOCKSTORE_UNKNOWN, healthRecord{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	record, ok := h.records[addr]
+	if !ok {
+		return BLOCKSTORE_UNKNOWN, healthRecord{}
+	}
+	return record.statusAt(h.now()), *record
+}
+
+// liveReplicas leaves out the replicas that are down, unless all of them are
+func (m *MetaStore) liveReplicas(replicas []string) []string {
+	health := m.health.Load()
+	if health == nil {
+		return replicas
+	}
+	live := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		if status, _ := health.status(replica); status != BLOCKSTORE_DOWN {
+			live = append(live, replica)
+		}
+	}
+	if len(live) == 0 {
+		return replicas
+	}
+	return live
+}
+
+func (m *MetaStore) GetClusterStatus(ctx context.Context, _ *emptypb.Empty) (*ClusterStatus, error) {
+	blockStores := m.blockStores().config
+	health := m.health.Load()
+	clusterStatus := &ClusterStatus{BlockStores: make([]*BlockStoreStatus, 0, len(blockStores.BlockStoreAddrs))}
+	for _, addr := range blockStores.BlockStoreAddrs {
+		status, record := health.status(addr)
+		blockStoreStatus := &BlockStoreStatus{
+			Addr:   addr,
+			NodeId: blockStores.NodeIds[addr],
+			Zone:   blockStores.Zones[addr],
+			Status: status,
+			Error:  record.err,
+		}
+		if record.answered {
+			blockStoreStatus.LastSeen = record.lastSeen.UnixMilli()
+		}
+		clusterStatus.BlockStores = append(clusterStatus.BlockStores, blockStoreStatus)
+	}
+	return clusterStatus, nil
+}
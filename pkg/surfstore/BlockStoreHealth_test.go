@@ -0,0 +1,99 @@
+package surfstore
+
+import (
+	context "context"
+	"testing"
+	"time"
+
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestHealthChecksMarkAStoppedBlockStoreSuspectThenDown(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ReplicationFactor = 2
+	health := newBlockStoreHealth()
+	now := time.Now()
+	health.now = func() time.Time { return now }
+	metaStore.health.Store(health)
+	live, stopped := blockStores[0], blockStores[1]
+
+	statuses := func() map[string]*BlockStoreStatus {
+		t.Helper()
+		clusterStatus, err := metaStore.GetClusterStatus(context.Background(), &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byAddr := map[string]*BlockStoreStatus{}
+		for _, blockStoreStatus := range clusterStatus.BlockStores {
+			byAddr[blockStoreStatus.Addr] = blockStoreStatus
+		}
+		if len(byAddr) != len(blockStores) {
+			t.Fatalf("the cluster status lists %d block stores, there are %d", len(byAddr), len(blockStores))
+		}
+		return byAddr
+	}
+	// the replicas GetBlockStoreMap lists for a block
+	replicas := func() []string {
+		t.Helper()
+		blockStoreMap, err := metaStore.GetBlockStoreMap(context.Background(), &BlockHashes{Hashes: []string{GetBlockHashString([]byte("a"))}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs := []string{}
+		for addr := range blockStoreMap.BlockStoreMap {
+			addrs = append(addrs, addr)
+		}
+		return addrs
+	}
+
+	if status := statuses()[live.Addr]; status.Status != BLOCKSTORE_UNKNOWN {
+		t.Fatalf("%s is %s before any probe", live.Addr, status.Status)
+	}
+	health.probe(metaStore.blockStores().config)
+	for addr, status := range statuses() {
+		if status.Status != BLOCKSTORE_UP || status.LastSeen != now.UnixMilli() || status.Error != "" {
+			t.Fatalf("%s answered but is %s, last seen %d, error %q", addr, status.Status, status.LastSeen, status.Error)
+		}
+	}
+
+	stopped.Stop()
+	lastAnswer := now
+	for _, step := range []struct {
+		after time.Duration
+		want  string
+	}{
+		{BLOCKSTORE_SUSPECT_TIMEOUT - time.Millisecond, BLOCKSTORE_UP},
+		{BLOCKSTORE_SUSPECT_TIMEOUT, BLOCKSTORE_SUSPECT},
+		{BLOCKSTORE_DOWN_TIMEOUT - time.Millisecond, BLOCKSTORE_SUSPECT},
+		{BLOCKSTORE_DOWN_TIMEOUT, BLOCKSTORE_DOWN},
+	} {
+		now = lastAnswer.Add(step.after)
+		health.probe(metaStore.blockStores().config)
+		status := statuses()
+		if status[stopped.Addr].Status != step.want {
+			t.Fatalf("%v after its last answer, %s is %s, want %s", step.after, stopped.Addr, status[stopped.Addr].Status, step.want)
+		}
+		if status[stopped.Addr].Error == "" {
+			t.Fatalf("%s does not answer, but has no error", stopped.Addr)
+		}
+		if status[live.Addr].Status != BLOCKSTORE_UP {
+			t.Fatalf("%s answers every probe, but is %s", live.Addr, status[live.Addr].Status)
+		}
+		// a suspect replica is still listed, a down one is not
+		if got := replicas(); len(got) != map[bool]int{true: 1, false: 2}[step.want == BLOCKSTORE_DOWN] {
+			t.Fatalf("%s is %s, and a block is placed on %v", stopped.Addr, step.want, got)
+		}
+	}
+	if got := replicas(); got[0] != live.Addr {
+		t.Fatalf("with %s down, a block is placed on %v", stopped.Addr, got)
+	}
+
+	// a replica that is down is still listed when a block has no other
+	now = now.Add(BLOCKSTORE_DOWN_TIMEOUT)
+	live.Stop()
+	health.probe(metaStore.blockStores().config)
+	if got := replicas(); len(got) != 2 {
+		t.Fatalf("with every replica down, a block is placed on %v", got)
+	}
+}
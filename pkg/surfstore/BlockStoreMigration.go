@@ -0,0 +1,414 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Adding or removing a block server moves the blocks whose replicas change:
+//  1. the ranges of the hash space (see merkleRange) whose replicas differ between the old and the
+//     new ring are worked out from the rings alone, and every block server lists the blocks it holds
+//     in those ranges. Stripe shards are placed by their stripe, the ones that move are asked for
+//  2. a block whose replicas differ is copied from a server holding it to each new replica missing
+//     it, the other blocks are left alone
+//  3. MissingBlocks on every target checks the copies
+//  4. only then the new ring is committed, so clients never look for a block where it is not yet
+//  5. the copy runs once more for blocks uploaded to the old replicas while we were migrating, and
+//     the change fails if it does not get through after a few attempts
+//
+// Blocks stay on the servers that are no longer their replicas until the next garbage collection.
+// It deletes such a copy once its replicas are found to have the block, copying it there first if
+// they do not, so a block a client uploaded with an old block map is not lost. A removed server can be shut down as
+// soon as RemoveBlockStore returns, or may already be down if the other replicas of its blocks are
+// enough to copy from.
+
+func (m *MetaStore) AddBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	return m.changeBlockStores(blockStoreAddr, true, m.SetBlockStores)
+}
+
+func (m *MetaStore) RemoveBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	return m.changeBlockStores(blockStoreAddr, false, m.SetBlockStores)
+}
+
+// changeBlockStores adds or removes one block server, commit makes the new ring take effect
+func (m *MetaStore) changeBlockStores(blockStoreAddr *BlockStoreAddr, add bool, commit func(blockStores *BlockStoreAddrs) error) (*MigrationReport, error) {
+	m.migrationMu.Lock()
+	defer m.migrationMu.Unlock()
+
+	addr := blockStoreAddr.Addr
+	oldBlockStores := m.blockStores().config
+	oldAddrs := oldBlockStores.BlockStoreAddrs
+	newAddrs := make([]string, 0, len(oldAddrs)+1)
+	found := false
+	for _, oldAddr := range oldAddrs {
+		if oldAddr == addr {
+			found = true
+			if !add {
+				continue
+			}
+		}
+		newAddrs = append(newAddrs, oldAddr)
+	}
+	switch {
+	case add && found:
+		return nil, status.Errorf(codes.AlreadyExists, "block store %s is already on the ring", addr)
+	case !add && !found:
+		return nil, status.Errorf(codes.NotFound, "block store %s is not on the ring", addr)
+	case !add && len(newAddrs) == 0:
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot remove the last block store %s", addr)
+	}
+	newBlockStores := &BlockStoreAddrs{
+		BlockStoreAddrs: newAddrs,
+		Weights:         map[string]int32{},
+		VirtualNodes:    oldBlockStores.VirtualNodes,
+		Placement:       oldBlockStores.Placement,
+		NodeIds:         map[string]string{},
+		Zones:           map[string]string{},
+	}
+	for _, newAddr := range newAddrs {
+		if weight, ok := oldBlockStores.Weights[newAddr]; ok {
+			newBlockStores.Weights[newAddr] = weight
+		}
+		if nodeId, ok := oldBlockStores.NodeIds[newAddr]; ok {
+			newBlockStores.NodeIds[newAddr] = nodeId
+		}
+		if zone, ok := oldBlockStores.Zones[newAddr]; ok {
+			newBlockStores.Zones[newAddr] = zone
+		}
+	}
+	if add {
+		// the new server is placed by the node ID it reports
+		nodeId := blockStoreAddr.NodeId
+		if nodeId == "" {
+			if err := (&RPCClient{}).GetNodeId(addr, &nodeId); err != nil {
+				return nil, status.Errorf(codes.Unavailable, "cannot get the node ID of %s: %v", addr, err)
+			}
+		}
+		for _, newAddr := range newAddrs {
+			if blockStoreNodeId(newBlockStores, newAddr) == nodeId {
+				return nil, status.Errorf(codes.AlreadyExists, "node ID %s of %s is already on the ring at %s", nodeId, addr, newAddr)
+			}
+		}
+		newBlockStores.BlockStoreAddrs = append(newAddrs, addr)
+		newBlockStores.NodeIds[addr] = nodeId
+		if blockStoreAddr.Weight > 0 {
+			newBlockStores.Weights[addr] = blockStoreAddr.Weight
+		}
+		if blockStoreAddr.Zone != "" {
+			newBlockStores.Zones[addr] = blockStoreAddr.Zone
+		}
+	}
+
+	report, err := migrateBlocks(oldBlockStores, newBlockStores, m.blockServers, m.stripeShardHashes())
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(newBlockStores); err != nil {
+		return nil, err
+	}
+	log.Printf("Block stores changed from %v to %v", oldAddrs, newBlockStores.BlockStoreAddrs)
+
+	for attempt := 1; ; attempt++ {
+		catchUp, err := migrateBlocks(oldBlockStores, newBlockStores, m.blockServers, m.stripeShardHashes())
+		if err == nil {
+			report.BlocksCopied += catchUp.BlocksCopied
+			report.BytesCopied += catchUp.BytesCopied
+			return report, nil
+		}
+		log.Printf("Error while copying blocks uploaded during the migration, attempt %d: %v", attempt, err)
+		if attempt == MIGRATION_CATCH_UP_ATTEMPTS {
+			// the ring is committed already. A late upload left on an old replica is kept there
+			// by garbage collection until it is on its new replicas
+			return nil, status.Errorf(codes.Unavailable, "block stores changed to %v, but the blocks uploaded during the migration were not all copied: %v", newBlockStores.BlockStoreAddrs, err)
+		}
+		time.Sleep(MIGRATION_CATCH_UP_RETRY)
+	}
+}
+
+// migrateBlocks copies every block whose replicas differ between the old and the new ring
+// onto its new replicas, and checks they all have it. place returns the replicas of a block on a ring,
+// shards are the blocks placed by the stripe they are in rather than by their hash
+func migrateBlocks(oldBlockStores *BlockStoreAddrs, newBlockStores *BlockStoreAddrs, place func(ring *blockStoreRing, hash string) []string, shards []string) (*MigrationReport, error) {
+	client := &RPCClient{}
+	oldAddrs, newAddrs := oldBlockStores.BlockStoreAddrs, newBlockStores.BlockStoreAddrs
+	oldRing := newBlockStoreRing(oldBlockStores)
+	newRing := newBlockStoreRing(newBlockStores)
+
+	// 1. block hash -> the servers holding it. A server being added may hold some blocks already
+	servers := append([]string{}, oldAddrs...)
+	for _, newAddr := range newAddrs {
+		if !containsString(oldAddrs, newAddr) {
+			servers = append(servers, newAddr)
+		}
+	}
+	holders := map[string]map[string]bool{}
+	hold := func(serverAddr string, hashes []string) {
+		for _, hash := range hashes {
+			if holders[hash] == nil {
+				holders[hash] = map[string]bool{}
+			}
+			holders[hash][serverAddr] = true
+		}
+	}
+	// a server that cannot answer is fine if it is the one being removed, it is gone already
+	listFailed := func(serverAddr string, err error) error {
+		if !containsString(newAddrs, serverAddr) {
+			log.Printf("Cannot list the blocks of %s, copying from the other replicas: %v", serverAddr, err)
+			return nil
+		}
+		return fmt.Errorf("cannot list the blocks of %s: %w", serverAddr, err)
+	}
+	ranges := changedRanges(oldRing, newRing, place)
+	log.Printf("Migrating the blocks of %d of %d ranges", len(ranges), merkleRanges)
+	if len(ranges) > 0 {
+		for _, serverAddr := range servers {
+			hashes := []string{}
+			if err := client.GetRangeHashes(serverAddr, &MerkleTreeRequest{Ranges: ranges}, &hashes); err != nil {
+				if err := listFailed(serverAddr, err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			hold(serverAddr, hashes)
+		}
+	}
+	// server -> the moving shards it may hold, asking for a shard touches it, which does no harm
+	asks := map[string][]string{}
+	for _, hash := range shards {
+		oldReplicas := place(oldRing, hash)
+		newReplicas := place(newRing, hash)
+		if CompareBlockHashList(oldReplicas, newReplicas) {
+			continue
+		}
+		for _, serverAddr := range servers {
+			if containsString(oldReplicas, serverAddr) || containsString(newReplicas, serverAddr) {
+				asks[serverAddr] = append(asks[serverAddr], hash)
+			}
+		}
+	}
+	for serverAddr, hashes := range asks {
+		for start := 0; start < len(hashes); start += HASH_BATCH_SIZE {
+			batch := hashes[start:min(start+HASH_BATCH_SIZE, len(hashes))]
+			missing := []string{}
+			if err := client.MissingBlocks(batch, serverAddr, &missing); err != nil {
+				if err := listFailed(serverAddr, err); err != nil {
+					return nil, err
+				}
+				break
+			}
+			held := make([]string, 0, len(batch))
+			for _, hash := range batch {
+				if !containsString(missing, hash) {
+					held = append(held, hash)
+				}
+			}
+			hold(serverAddr, held)
+		}
+	}
+
+	// 2. source and target -> the blocks to copy
+	copies := map[[2]string][]string{}
+	for hash, holding := range holders {
+		oldReplicas := place(oldRing, hash)
+		newReplicas := place(newRing, hash)
+		if CompareBlockHashList(oldReplicas, newReplicas) {
+			continue
+		}
+		source := blockSource(holding, oldReplicas)
+		for _, target := range newReplicas {
+			if !holding[target] {
+				copies[[2]string{source, target}] = append(copies[[2]string{source, target}], hash)
+			}
+		}
+	}
+
+	report := &MigrationReport{}
+	for pair, hashes := range copies {
+		bytesCopied, err := copyBlocks(client, pair[0], pair[1], hashes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot copy blocks from %s to %s: %w", pair[0], pair[1], err)
+		}
+		report.BlocksCopied += int64(len(hashes))
+		report.BytesCopied += bytesCopied
+	}
+
+	// 3. trust the targets, not the streams
+	for pair, hashes := range copies {
+		for start := 0; start < len(hashes); start += HASH_BATCH_SIZE {
+			missing := []string{}
+			if err := client.MissingBlocks(hashes[start:min(start+HASH_BATCH_SIZE, len(hashes))], pair[1], &missing); err != nil {
+				return nil, fmt.Errorf("cannot verify the blocks copied to %s: %w", pair[1], err)
+			}
+			if len(missing) > 0 {
+				return nil, fmt.Errorf("%d blocks copied to %s are missing", len(missing), pair[1])
+			}
+		}
+	}
+	return report, nil
+}
+
+// listBlocks lists every block a server holds a few ranges at a time, so no answer gets too large
+func listBlocks(client *RPCClient, serverAddr string) ([]string, error) {
+	const rangesPerCall = 16
+	hashes := make([]string, 0)
+	for start := 0; start < merkleRanges; start += rangesPerCall {
+		ranges := make([]int32, 0, rangesPerCall)
+		for r := start; r < min(start+rangesPerCall, merkleRanges); r++ {
+			ranges = append(ranges, int32(r))
+		}
+		inRanges := []string{}
+		if err := client.GetRangeHashes(serverAddr, &MerkleTreeRequest{Ranges: ranges}, &inRanges); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, inRanges...)
+	}
+	return hashes, nil
+}
+
+// rehomeBlocks makes sure the replicas of hashes have them before serverAddr, which holds them
+// without being one of their replicas, lets them go: a replica missing one gets it copied from
+// serverAddr. It returns the blocks it could not make sure of, serverAddr has to keep those
+func rehomeBlocks(client *RPCClient, serverAddr string, hashes []string, replicasOf func(hash string) []string) []string {
+	unsafe := map[string]bool{}
+	targets := map[string][]string{}
+	for _, hash := range hashes {
+		for _, replica := range replicasOf(hash) {
+			targets[replica] = append(targets[replica], hash)
+		}
+	}
+	for target, hashes := range targets {
+		missing, err := missingBlocks(*client, target, hashes, syncedBlocks{})
+		if err == nil && len(missing) > 0 {
+			log.Printf("Copying %d blocks only %s still has to %s", len(missing), serverAddr, target)
+			if _, err = copyBlocks(client, serverAddr, target, missing); err == nil {
+				missing, err = missingBlocks(*client, target, missing, syncedBlocks{})
+			}
+		}
+		if err != nil {
+			log.Printf("Cannot make sure %s has the blocks %s holds for it: %v", target, serverAddr, err)
+			missing = hashes
+		}
+		for _, hash := range missing {
+			unsafe[hash] = true
+		}
+	}
+	kept := make([]string, 0, len(unsafe))
+	for hash := range unsafe {
+		kept = append(kept, hash)
+	}
+	return kept
+}
+
+// changedRanges lists the ranges holding a hash whose replicas differ between the old and the new
+// ring. A ring places whole pieces of the hash space between its boundaries, so cutting the hash space
+// at the boundaries of both rings gives pieces placed the same way on each, and placing the first
+// hash of a piece is enough. Without boundaries, as with rendezvous hashing, any block may move
+// and every range is listed
+func changedRanges(oldRing *blockStoreRing, newRing *blockStoreRing, place func(ring *blockStoreRing, hash string) []string) []int32 {
+	changed := make([]bool, merkleRanges)
+	oldBoundaries, oldOk := ringBoundaries(oldRing)
+	newBoundaries, newOk := ringBoundaries(newRing)
+	if !oldOk || !newOk {
+		for r := range changed {
+			changed[r] = true
+		}
+	} else {
+		// "0" sorts before every block hash, its piece wraps around to the first boundary
+		boundaries := append(append([]string{"0"}, oldBoundaries...), newBoundaries...)
+		sort.Strings(boundaries)
+		boundaries = slices.Compact(boundaries)
+		for i, start := range boundaries {
+			if CompareBlockHashList(place(oldRing, start), place(newRing, start)) {
+				continue
+			}
+			last := merkleRanges - 1
+			if i+1 < len(boundaries) {
+				last = merkleRange(boundaries[i+1])
+			}
+			for r := merkleRange(start); r <= last; r++ {
+				changed[r] = true
+			}
+		}
+	}
+	ranges := make([]int32, 0)
+	for r, ok := range changed {
+		if ok {
+			ranges = append(ranges, int32(r))
+		}
+	}
+	return ranges
+}
+
+func ringBoundaries(ring *blockStoreRing) ([]string, bool) {
+	placement, ok := ring.placement.(rangePlacement)
+	if !ok {
+		return nil, false
+	}
+	return placement.boundaries()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// blockSource picks the server to copy a block from, preferring one of its old replicas
+func blockSource(holding map[string]bool, oldReplicas []string) string {
+	for _, replica := range oldReplicas {
+		if holding[replica] {
+			return replica
+		}
+	}
+	holders := make([]string, 0, len(holding))
+	for holder := range holding {
+		holders = append(holders, holder)
+	}
+	sort.Strings(holders)
+	return holders[0]
+}
+
+// copyBlocks streams blocks from source straight into target, HASH_BATCH_SIZE blocks per stream
+func copyBlocks(client *RPCClient, source string, target string, hashes []string) (int64, error) {
+	bytesCopied := int64(0)
+	for start := 0; start < len(hashes); start += HASH_BATCH_SIZE {
+		batch := hashes[start:min(start+HASH_BATCH_SIZE, len(hashes))]
+		done := make(chan struct{})
+		stream := startBlockStream(*client, source, batch, done)
+		next := 0
+		var success bool
+		err := client.PutBlocks(target, func() (*Block, error) {
+			if next == len(batch) {
+				return nil, io.EOF
+			}
+			block, ok := <-stream.blocks
+			if !ok {
+				return nil, stream.err
+			}
+			// the target turns away a block the source sent corrupted
+			block.Hash = batch[next]
+			next++
+			bytesCopied += int64(len(block.BlockData))
+			return block, nil
+		}, &success)
+		close(done)
+		if err != nil {
+			return 0, err
+		}
+		if !success {
+			return 0, fmt.Errorf("%s did not store the blocks", target)
+		}
+	}
+	return bytesCopied, nil
+}
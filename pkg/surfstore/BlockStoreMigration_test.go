@@ -0,0 +1,178 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"testing"
+)
+
+func TestAddBlockStoreMovesChangedRangesAndSweepsOldCopies(t *testing.T) {
+	blockStores := startTestBlockStores(t, 4)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores[:3]))
+	metaStore.ReplicationFactor = 2
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{"a.bin": 64 * 1024, "b.bin": 40*1024 + 3})
+	ClientSync(uploader)
+
+	oldRing := metaStore.blockStores()
+	if _, err := metaStore.AddBlockStore(context.Background(), &BlockStoreAddr{Addr: blockStores[3].Addr}); err != nil {
+		t.Fatal(err)
+	}
+	newRing := metaStore.blockStores()
+	ranges := changedRanges(oldRing, newRing, metaStore.blockServers)
+	if len(ranges) == 0 || len(ranges) == merkleRanges {
+		t.Fatalf("%d of %d ranges changed, a new server takes some of them", len(ranges), merkleRanges)
+	}
+
+	// every block is on its new replicas, and the old copies go with the next garbage collection
+	if _, err := metaStore.CollectGarbage(context.Background(), &GarbageCollection{}); err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]*BlockStore{}
+	for _, blockStore := range blockStores {
+		byAddr[blockStore.Addr] = blockStore.BlockStore
+	}
+	moved := 0
+	for _, hash := range metaStore.liveHashes() {
+		replicas := metaStore.blockServers(newRing, hash)
+		if !CompareBlockHashList(replicas, metaStore.blockServers(oldRing, hash)) {
+			if !containsRange(ranges, merkleRange(hash)) {
+				t.Fatalf("block %s moved, but its range %d was not scanned", hash, merkleRange(hash))
+			}
+			moved++
+		}
+		for addr, blockStore := range byAddr {
+			has, _ := blockStore.Storage.Has(hash)
+			if containsString(replicas, addr) && !has {
+				t.Fatalf("replica %s of block %s does not have it", addr, hash)
+			}
+			if !containsString(replicas, addr) && has {
+				t.Fatalf("%s still holds block %s after garbage collection", addr, hash)
+			}
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no block moved to the new server")
+	}
+
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+}
+
+func TestChangedRanges(t *testing.T) {
+	oldRing := newBlockStoreRing(&BlockStoreAddrs{BlockStoreAddrs: []string{"a:1", "b:1"}, Placement: PLACEMENT_RENDEZVOUS})
+	newRing := newBlockStoreRing(&BlockStoreAddrs{BlockStoreAddrs: []string{"a:1", "b:1", "c:1"}, Placement: PLACEMENT_RENDEZVOUS})
+	place := func(ring *blockStoreRing, hash string) []string { return ring.responsibleServers(hash, 1) }
+	if ranges := changedRanges(oldRing, newRing, place); len(ranges) != merkleRanges {
+		t.Fatalf("rendezvous hashing may move any block, but only %d ranges are scanned", len(ranges))
+	}
+	if ranges := changedRanges(oldRing, oldRing, place); len(ranges) != merkleRanges {
+		t.Fatalf("%d ranges are scanned, rendezvous hashing has no ranges to leave out", len(ranges))
+	}
+
+	// every hash that moves is in a range that is scanned
+	for _, placement := range []string{PLACEMENT_CONSISTENT, PLACEMENT_BOUNDED} {
+		oldRing = newBlockStoreRing(&BlockStoreAddrs{BlockStoreAddrs: []string{"a:1", "b:1", "c:1"}, Placement: placement, VirtualNodes: 4})
+		newRing = newBlockStoreRing(&BlockStoreAddrs{BlockStoreAddrs: []string{"a:1", "b:1", "c:1", "d:1"}, Placement: placement, VirtualNodes: 4})
+		place := func(ring *blockStoreRing, hash string) []string { return ring.responsibleServers(hash, 2) }
+		if ranges := changedRanges(oldRing, oldRing, place); len(ranges) != 0 {
+			t.Fatalf("%s: %d ranges changed on a ring that did not change", placement, len(ranges))
+		}
+		ranges := changedRanges(oldRing, newRing, place)
+		for i := 0; i < 10000; i++ {
+			hash := GetBlockHashString([]byte(fmt.Sprint(i)))
+			if !CompareBlockHashList(place(oldRing, hash), place(newRing, hash)) && !containsRange(ranges, merkleRange(hash)) {
+				t.Fatalf("%s: block %s moves, but its range %d is not scanned", placement, hash, merkleRange(hash))
+			}
+		}
+	}
+}
+
+func containsRange(ranges []int32, r int) bool {
+	for _, changed := range ranges {
+		if int(changed) == r {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGarbageCollectionMovesStrayLiveBlocksBeforeSweeping(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{"a.bin": 20 * 1024})
+	ClientSync(uploader)
+
+	// every block only on the server that is not its replica, as if uploaded with an old block map
+	ring := metaStore.blockStores()
+	byAddr := map[string]*BlockStore{}
+	for _, blockStore := range blockStores {
+		byAddr[blockStore.Addr] = blockStore.BlockStore
+	}
+	strays := map[string]string{}
+	for _, hash := range metaStore.liveHashes() {
+		replica := metaStore.blockServers(ring, hash)[0]
+		for addr, blockStore := range byAddr {
+			if addr != replica {
+				block, _, _ := byAddr[replica].Storage.Get(hash)
+				if err := blockStore.Storage.Put(hash, block); err != nil {
+					t.Fatal(err)
+				}
+				strays[hash] = addr
+			}
+		}
+		if err := byAddr[replica].Storage.Delete(hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := metaStore.CollectGarbage(context.Background(), &GarbageCollection{}); err != nil {
+		t.Fatal(err)
+	}
+	for hash, stray := range strays {
+		replica := metaStore.blockServers(ring, hash)[0]
+		if has, _ := byAddr[replica].Storage.Has(hash); !has {
+			t.Fatalf("block %s was not copied to its replica %s", hash, replica)
+		}
+		if has, _ := byAddr[stray].Storage.Has(hash); has {
+			t.Fatalf("block %s is still on %s, which is not its replica", hash, stray)
+		}
+	}
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+}
+
+func TestGarbageCollectionKeepsStrayLiveBlocksItCannotMove(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	hash := GetBlockHashString([]byte("a"))
+	if _, err := metaStore.UpdateFile(context.Background(), &FileMetaData{Filename: "a.bin", Version: 1, BlockHashList: []string{hash}}); err != nil {
+		t.Fatal(err)
+	}
+	replica := metaStore.blockServers(metaStore.blockStores(), hash)[0]
+	var stray *testBlockStore
+	for _, blockStore := range blockStores {
+		if blockStore.Addr == replica {
+			blockStore.Stop()
+		} else {
+			stray = blockStore
+		}
+	}
+	if err := stray.BlockStore.Storage.Put(hash, &Block{BlockData: []byte("a"), BlockSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// the sweep of the replica fails, the stray copy is kept all the same
+	if _, err := metaStore.CollectGarbage(context.Background(), &GarbageCollection{}); err == nil {
+		t.Fatal("garbage collection succeeded with a block server down")
+	}
+	if has, _ := stray.BlockStore.Storage.Has(hash); !has {
+		t.Fatal("the only copy of a live block was swept while its replica was down")
+	}
+}
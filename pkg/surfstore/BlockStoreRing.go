@@ -0,0 +1,228 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockStoreRing is one version of the ring together with the configuration it was built from.
+// The placement strategy places blocks by node ID, so a block server keeps its blocks when its
+// address changes. A server without a known node ID goes by its address.
+type blockStoreRing struct {
+	config    *BlockStoreAddrs
+	placement PlacementStrategy
+	// node ID -> address
+	addrs map[string]string
+}
+
+func newBlockStoreRing(blockStores *BlockStoreAddrs) *blockStoreRing {
+	nodeIds := make([]string, 0, len(blockStores.BlockStoreAddrs))
+	weights := map[string]int32{}
+	zones := map[string]string{}
+	addrs := map[string]string{}
+	for _, addr := range blockStores.BlockStoreAddrs {
+		nodeId := blockStoreNodeId(blockStores, addr)
+		nodeIds = append(nodeIds, nodeId)
+		if weight, ok := blockStores.Weights[addr]; ok {
+			weights[nodeId] = weight
+		}
+		if zone, ok := blockStores.Zones[addr]; ok {
+			zones[nodeId] = zone
+		}
+		addrs[nodeId] = addr
+	}
+	placement, err := NewPlacementStrategy(blockStores.Placement, nodeIds, weights, int(blockStores.VirtualNodes))
+	if err != nil {
+		// the server checks -placement, this is a configuration saved by a newer version
+		log.Printf("%v, placing blocks with %s", err, PLACEMENT_CONSISTENT)
+		placement = NewWeightedConsistentHashRing(nodeIds, weights, int(blockStores.VirtualNodes))
+	}
+	if len(zones) > 0 {
+		placement = NewZoneAwarePlacement(placement, nodeIds, zones)
+	}
+	return &blockStoreRing{
+		config:    blockStores,
+		placement: placement,
+		addrs:     addrs,
+	}
+}
+
+// responsibleServers returns the addresses of the n block servers holding blockHash
+func (r *blockStoreRing) responsibleServers(blockHash string, n int) []string {
+	servers := r.placement.GetResponsibleServers(blockHash, n)
+	for i, nodeId := range servers {
+		servers[i] = r.addrs[nodeId]
+	}
+	return servers
+}
+
+func blockStoreNodeId(blockStores *BlockStoreAddrs, addr string) string {
+	if nodeId, ok := blockStores.NodeIds[addr]; ok && nodeId != "" {
+		return nodeId
+	}
+	return addr
+}
+
+// SetBlockStores replaces the configuration of the ring, saving it first if the MetaStore is durable
+func (m *MetaStore) SetBlockStores(blockStores *BlockStoreAddrs) error {
+	m.RWMutex.Lock()
+	defer m.RWMutex.Unlock()
+	if m.Log != nil {
+		if err := m.Log.SaveBlockStores(blockStores); err != nil {
+			return err
+		}
+	}
+	// replaced, never changed in place, so readers holding the old ones are not disturbed
+	ring := newBlockStoreRing(blockStores)
+	m.BlockStores = blockStores
+	m.BlockStoreAddrs = blockStores.BlockStoreAddrs
+	m.ConsistentHashRing, _ = ring.placement.(*ConsistentHashRing)
+	m.ring.Store(ring)
+	return nil
+}
+
+// blockStores returns the current ring, lookups never wait for a lock
+func (m *MetaStore) blockStores() *blockStoreRing {
+	if current := m.ring.Load(); current != nil {
+		return current
+	}
+	// a MetaStore put together without a constructor
+	m.RWMutex.RLock()
+	defer m.RWMutex.RUnlock()
+	if m.BlockStores == nil {
+		return newBlockStoreRing(&BlockStoreAddrs{BlockStoreAddrs: m.BlockStoreAddrs})
+	}
+	return newBlockStoreRing(m.BlockStores)
+}
+
+// SetBlockStoreAddr points the node ID of a block server at a new address. The ring does not change,
+// so no block moves, but the server must answer at the new address with that node ID
+func (m *MetaStore) SetBlockStoreAddr(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*Success, error) {
+	return m.changeBlockStoreAddr(blockStoreAddr, m.SetBlockStores)
+}
+
+func (m *MetaStore) changeBlockStoreAddr(blockStoreAddr *BlockStoreAddr, commit func(blockStores *BlockStoreAddrs) error) (*Success, error) {
+	m.migrationMu.Lock()
+	defer m.migrationMu.Unlock()
+
+	oldBlockStores := m.blockStores().config
+	oldAddr := ""
+	for _, addr := range oldBlockStores.BlockStoreAddrs {
+		if blockStoreNodeId(oldBlockStores, addr) == blockStoreAddr.NodeId {
+			oldAddr = addr
+		}
+	}
+	if oldAddr == "" {
+		return nil, status.Errorf(codes.NotFound, "no block store with node ID %s", blockStoreAddr.NodeId)
+	}
+	if oldAddr != blockStoreAddr.Addr && containsString(oldBlockStores.BlockStoreAddrs, blockStoreAddr.Addr) {
+		return nil, status.Errorf(codes.AlreadyExists, "block store %s is already on the ring", blockStoreAddr.Addr)
+	}
+	nodeId := ""
+	if err := (&RPCClient{}).GetNodeId(blockStoreAddr.Addr, &nodeId); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "cannot reach block store at %s: %v", blockStoreAddr.Addr, err)
+	}
+	if nodeId != blockStoreAddr.NodeId {
+		return nil, status.Errorf(codes.FailedPrecondition, "block store at %s has node ID %s, not %s", blockStoreAddr.Addr, nodeId, blockStoreAddr.NodeId)
+	}
+
+	newBlockStores := &BlockStoreAddrs{
+		BlockStoreAddrs: make([]string, 0, len(oldBlockStores.BlockStoreAddrs)),
+		Weights:         map[string]int32{},
+		VirtualNodes:    oldBlockStores.VirtualNodes,
+		Placement:       oldBlockStores.Placement,
+		NodeIds:         map[string]string{},
+		Zones:           map[string]string{},
+	}
+	for _, addr := range oldBlockStores.BlockStoreAddrs {
+		newAddr := addr
+		if addr == oldAddr {
+			newAddr = blockStoreAddr.Addr
+		}
+		newBlockStores.BlockStoreAddrs = append(newBlockStores.BlockStoreAddrs, newAddr)
+		if weight, ok := oldBlockStores.Weights[addr]; ok {
+			newBlockStores.Weights[newAddr] = weight
+		}
+		if zone, ok := oldBlockStores.Zones[addr]; ok {
+			newBlockStores.Zones[newAddr] = zone
+		}
+		newBlockStores.NodeIds[newAddr] = blockStoreNodeId(oldBlockStores, addr)
+	}
+	if err := commit(newBlockStores); err != nil {
+		return nil, err
+	}
+	log.Printf("Block store %s moved from %s to %s", blockStoreAddr.NodeId, oldAddr, blockStoreAddr.Addr)
+	return &Success{Flag: true}, nil
+}
+
+// RegisterBlockStore is how a block server started with -meta joins the ring. Registering again is a
+// no-op, and a block server whose node ID is on the ring at another address only gets its address changed
+func (m *MetaStore) RegisterBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	return m.registerBlockStore(blockStoreAddr, m.SetBlockStores)
+}
+
+func (m *MetaStore) registerBlockStore(blockStoreAddr *BlockStoreAddr, commit func(blockStores *BlockStoreAddrs) error) (*MigrationReport, error) {
+	nodeId := ""
+	if err := (&RPCClient{}).GetNodeId(blockStoreAddr.Addr, &nodeId); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "cannot get the node ID of %s: %v", blockStoreAddr.Addr, err)
+	}
+	blockStores := m.blockStores().config
+	for _, addr := range blockStores.BlockStoreAddrs {
+		onRing := blockStoreNodeId(blockStores, addr)
+		switch {
+		case addr == blockStoreAddr.Addr && (onRing == addr || onRing == nodeId):
+			// placed by its address until now, or registered already
+			return &MigrationReport{}, nil
+		case addr == blockStoreAddr.Addr:
+			return nil, status.Errorf(codes.FailedPrecondition, "block store %s is on the ring with node ID %s, not %s", addr, onRing, nodeId)
+		case onRing == nodeId:
+			if _, err := m.changeBlockStoreAddr(&BlockStoreAddr{Addr: blockStoreAddr.Addr, NodeId: nodeId}, commit); err != nil {
+				return nil, err
+			}
+			return &MigrationReport{}, nil
+		}
+	}
+	return m.changeBlockStores(&BlockStoreAddr{
+		Addr:   blockStoreAddr.Addr,
+		Weight: blockStoreAddr.Weight,
+		NodeId: nodeId,
+		Zone:   blockStoreAddr.Zone,
+	}, true, commit)
+}
+
+// ParseBlockStoreArg parses a block server given on the command line:
+// host:port, optionally followed by ,weight=n ,id=nodeId and ,zone=name
+func ParseBlockStoreArg(arg string) (*BlockStoreAddr, error) {
+	fields := strings.Split(arg, CONFIG_DELIMITER)
+	blockStoreAddr := &BlockStoreAddr{Addr: fields[0], Weight: 1}
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "weight":
+			w, err := strconv.Atoi(value)
+			if err != nil || w < 1 {
+				return nil, fmt.Errorf("invalid weight in %s", arg)
+			}
+			blockStoreAddr.Weight = int32(w)
+		case "id":
+			if value == "" {
+				return nil, fmt.Errorf("empty node ID in %s", arg)
+			}
+			blockStoreAddr.NodeId = value
+		case "zone":
+			if value == "" {
+				return nil, fmt.Errorf("empty zone in %s", arg)
+			}
+			blockStoreAddr.Zone = value
+		default:
+			return nil, fmt.Errorf("unknown option %s in %s", key, arg)
+		}
+	}
+	return blockStoreAddr, nil
+}
@@ -0,0 +1,44 @@
+package surfstore
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateBlock(t *testing.T) {
+	data := []byte("some block")
+	hash := GetBlockHashString(data)
+	blockStore := NewBlockStore()
+	blockStore.MaxBlockSize = len(data)
+	for _, test := range []struct {
+		name  string
+		block *Block
+		want  codes.Code
+	}{
+		{"valid", &Block{BlockData: data, BlockSize: int32(len(data)), Hash: hash}, codes.OK},
+		{"valid without a hash", &Block{BlockData: data, BlockSize: int32(len(data))}, codes.OK},
+		{"empty", &Block{BlockData: []byte{}, BlockSize: 0}, codes.OK},
+		{"larger than the limit", &Block{BlockData: append(data, '!'), BlockSize: int32(len(data) + 1)}, codes.ResourceExhausted},
+		{"size too small", &Block{BlockData: data, BlockSize: int32(len(data) - 1), Hash: hash}, codes.InvalidArgument},
+		{"size too large", &Block{BlockData: data, BlockSize: int32(len(data) + 1), Hash: hash}, codes.InvalidArgument},
+		{"hash mismatch", &Block{BlockData: data, BlockSize: int32(len(data)), Hash: GetBlockHashString([]byte("another block"))}, codes.InvalidArgument},
+		{"hash not hex", &Block{BlockData: data, BlockSize: int32(len(data)), Hash: "../../etc/passwd"}, codes.InvalidArgument},
+	} {
+		got, err := blockStore.validateBlock(test.block)
+		if code := status.Code(err); code != test.want {
+			t.Fatalf("%s: got %v, want %v", test.name, err, test.want)
+		}
+		if err == nil && got != GetBlockHashString(test.block.BlockData) {
+			t.Fatalf("%s: validated as %s, the block hashes to %s", test.name, got, GetBlockHashString(test.block.BlockData))
+		}
+	}
+
+	// no limit without MaxBlockSize
+	blockStore.MaxBlockSize = 0
+	large := make([]byte, 2*DEFAULT_MAX_BLOCK_SIZE)
+	if _, err := blockStore.validateBlock(&Block{BlockData: large, BlockSize: int32(len(large))}); err != nil {
+		t.Fatalf("a block of %d bytes was refused without a limit: %v", len(large), err)
+	}
+}
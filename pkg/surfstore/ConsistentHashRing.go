@@ -4,35 +4,89 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"sort"
+	"strconv"
 )
 
+// ConsistentHashRing is immutable once built: lookups binary-search a sorted index of the points,
+// so any number of goroutines can share one. A membership change builds a new ring.
 type ConsistentHashRing struct {
 	// used to store the hash value of the server and the server address in the hash ring
+	// every virtual node of a server is one entry
 	ServerMap map[string]string
+	// the keys of ServerMap sorted, and the server of each
+	hashes  []string
+	servers []string
 }
 
 func (c ConsistentHashRing) GetResponsibleServer(blockId string) string {
 	// panic("todo")
 	// follow the discussion code, find where each block belongs to
 	// ------------------------------------------------
-	// 1. sort hash values (key in hash ring)
-	hashes := []string{}
-	for h := range c.ServerMap {
-		hashes = append(hashes, h)
-	}
-	sort.Strings(hashes)
-	// 2. find the first server with larger hash value than blockHash
-	responsibleServer := ""
-	for i := 0; i < len(hashes); i++ {
-		if hashes[i] > blockId {
-			responsibleServer = c.ServerMap[hashes[i]]
-			break
+	// the first server with larger hash value than blockHash, wrapping around to the first one
+	c = c.indexed()
+	if len(c.hashes) == 0 {
+		return ""
+	}
+	return c.servers[c.successor(blockId)] // return the server address
+}
+
+// GetResponsibleServers returns the n distinct servers that follow blockId on the ring,
+// the first one is GetResponsibleServer. Fewer are returned if the ring has fewer servers.
+func (c ConsistentHashRing) GetResponsibleServers(blockId string, n int) []string {
+	c = c.indexed()
+	servers := make([]string, 0, n)
+	if len(c.hashes) == 0 {
+		return servers
+	}
+	start := c.successor(blockId)
+	for i := 0; i < len(c.hashes) && len(servers) < n; i++ {
+		server := c.servers[(start+i)%len(c.hashes)]
+		// n is small, a scan is cheaper than a set
+		if !containsString(servers, server) {
+			servers = append(servers, server)
 		}
 	}
-	if responsibleServer == "" {
-		responsibleServer = c.ServerMap[hashes[0]]
+	return servers
+}
+
+// successor is the index of the first point with a larger hash value than blockId, wrapping around
+func (c ConsistentHashRing) successor(blockId string) int {
+	i := sort.Search(len(c.hashes), func(i int) bool { return c.hashes[i] > blockId })
+	if i == len(c.hashes) {
+		return 0
 	}
-	return responsibleServer // return the server address
+	return i
+}
+
+// boundaries are the points of the ring: a point belongs to the range after it, since only a larger
+// hash value has it as its successor
+func (c ConsistentHashRing) boundaries() ([]string, bool) {
+	return c.indexed().hashes, true
+}
+
+// indexed returns the ring with its sorted index. Rings from the constructors have it already,
+// one put together by hand from a ServerMap gets it built here, for this call only
+func (c ConsistentHashRing) indexed() ConsistentHashRing {
+	if len(c.hashes) == len(c.ServerMap) {
+		return c
+	}
+	return *newIndexedRing(c.ServerMap)
+}
+
+func newIndexedRing(serverMap map[string]string) *ConsistentHashRing {
+	c := &ConsistentHashRing{
+		ServerMap: serverMap,
+		hashes:    make([]string, 0, len(serverMap)),
+		servers:   make([]string, len(serverMap)),
+	}
+	for h := range serverMap {
+		c.hashes = append(c.hashes, h)
+	}
+	sort.Strings(c.hashes)
+	for i, h := range c.hashes {
+		c.servers[i] = serverMap[h]
+	}
+	return c
 }
 
 // address -> hash; eg:blockstorelocalhost:8082 -> 12
@@ -42,17 +96,32 @@ func (c ConsistentHashRing) Hash(addr string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func NewConsistentHashRing(serverAddrs []string) *ConsistentHashRing {
-	//panic("todo")
-	consistentHashRing := &ConsistentHashRing{
-		ServerMap: make(map[string]string),
+// NewWeightedConsistentHashRing gives every server virtualNodes points on the ring for each unit of
+// its weight, so a server of weight 2 takes about twice the blocks. Missing weights count as 1.
+// Point 0 of a server is the one NewConsistentHashRing uses, so one virtual node of weight 1 places
+// blocks exactly like the plain ring
+func NewWeightedConsistentHashRing(serverAddrs []string, weights map[string]int32, virtualNodes int) *ConsistentHashRing {
+	if virtualNodes < 1 {
+		virtualNodes = 1
 	}
+	serverMap := make(map[string]string)
 	for _, serverAddr := range serverAddrs {
-		// fix: shoud hash blockstorelocalhost:8082 instead of localhost:8082 oh...no
-		// serverAddr = "blockstore" + serverAddr wrong, this will change the serverAddr
-		// consistentHashRing.ServerMap[consistentHashRing.Hash("blockstore"+serverAddr)] = serverAddr
-		hashKey := "blockstore" + serverAddr
-		consistentHashRing.ServerMap[consistentHashRing.Hash(hashKey)] = serverAddr
+		for i := 0; i < virtualNodes*serverWeight(weights, serverAddr); i++ {
+			serverMap[ConsistentHashRing{}.Hash(virtualNodeKey(serverAddr, i))] = serverAddr
+		}
 	}
-	return consistentHashRing
+	return newIndexedRing(serverMap)
+}
+
+// blockstorelocalhost:8082 for the first point of a server, blockstorelocalhost:8082#1, #2... for the others
+func virtualNodeKey(serverAddr string, i int) string {
+	if i == 0 {
+		return "blockstore" + serverAddr
+	}
+	return "blockstore" + serverAddr + "#" + strconv.Itoa(i)
+}
+func NewConsistentHashRing(serverAddrs []string) *ConsistentHashRing {
+	//panic("todo")
+	// every server is one point: hash of blockstorelocalhost:8082, not of localhost:8082
+	return NewWeightedConsistentHashRing(serverAddrs, nil, 1)
 }
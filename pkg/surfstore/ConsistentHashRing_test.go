@@ -0,0 +1,73 @@
+package surfstore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// Mapping the blocks of a file with the sorted index of the ring, against sorting the points on every
+// lookup, which is what GetResponsibleServer used to do:
+//
+//	go test ./pkg/surfstore -run '^$' -bench GetResponsibleServer
+func BenchmarkGetResponsibleServer(b *testing.B) {
+	benchmarkRing(b, func(ring *ConsistentHashRing, blockHash string) string {
+		return ring.GetResponsibleServer(blockHash)
+	})
+}
+
+func BenchmarkGetResponsibleServerSortOnEveryLookup(b *testing.B) {
+	benchmarkRing(b, func(ring *ConsistentHashRing, blockHash string) string {
+		return sortOnEveryLookup(ring.ServerMap, blockHash)
+	})
+}
+
+// benchmarkRing looks up the blocks of a file of 1000 blocks per op, on rings of several sizes
+func benchmarkRing(b *testing.B, lookup func(ring *ConsistentHashRing, blockHash string) string) {
+	blockHashes := make([]string, 1000)
+	for i := range blockHashes {
+		blockHashes[i] = GetBlockHashString([]byte(strconv.Itoa(i)))
+	}
+	for _, servers := range []int{3, 30, 100} {
+		for _, virtualNodes := range []int{1, 10} {
+			addrs := make([]string, servers)
+			for i := range addrs {
+				addrs[i] = "localhost:" + strconv.Itoa(8081+i)
+			}
+			ring := NewWeightedConsistentHashRing(addrs, nil, virtualNodes)
+			b.Run(fmt.Sprintf("servers=%d/vnodes=%d", servers, virtualNodes), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for _, blockHash := range blockHashes {
+						lookup(ring, blockHash)
+					}
+				}
+			})
+		}
+	}
+}
+
+// sortOnEveryLookup is the lookup without an index: sort every point, then scan for the successor
+func sortOnEveryLookup(serverMap map[string]string, blockId string) string {
+	hashes := []string{}
+	for h := range serverMap {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	for i := 0; i < len(hashes); i++ {
+		if hashes[i] > blockId {
+			return serverMap[hashes[i]]
+		}
+	}
+	return serverMap[hashes[0]]
+}
+
+func TestSortOnEveryLookupAgreesWithTheIndex(t *testing.T) {
+	ring := NewWeightedConsistentHashRing([]string{"localhost:8081", "localhost:8082", "localhost:8083"}, nil, 10)
+	for i := 0; i < 1000; i++ {
+		blockHash := GetBlockHashString([]byte(strconv.Itoa(i)))
+		if got, want := ring.GetResponsibleServer(blockHash), sortOnEveryLookup(ring.ServerMap, blockHash); got != want {
+			t.Fatalf("block %s goes to %s with the index, %s without", blockHash, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package surfstore
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskBlockStorage keeps every block as its own file under Dir/blocks.
+// Blocks are content addressed and sharded by the first bytes of the hash:
+// Dir/blocks/ab/cd/abcd...ef
+type DiskBlockStorage struct {
+	Dir string
+}
+
+const blockDirName string = "blocks"
+
+func (s *DiskBlockStorage) Get(hash string) (*Block, bool, error) {
+	if !isBlockHash(hash) {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(s.blockPath(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &Block{BlockData: data, BlockSize: int32(len(data))}, true, nil
+}
+
+// Put writes the block atomically, so a crash never leaves a half written block under a valid hash.
+// A block already stored is only touched if it still hashes right, a corrupted one is rewritten
+func (s *DiskBlockStorage) Put(hash string, block *Block) error {
+	if !isBlockHash(hash) {
+		return errors.New("invalid block hash " + hash)
+	}
+	path := s.blockPath(hash)
+	if data, err := os.ReadFile(path); err == nil && GetBlockHashString(data) == hash {
+		return s.Touch(hash)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, block.BlockData)
+}
+
+func (s *DiskBlockStorage) Has(hash string) (bool, error) {
+	if !isBlockHash(hash) {
+		return false, nil
+	}
+	_, err := os.Stat(s.blockPath(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DiskBlockStorage) Hashes() ([]string, error) {
+	hashes := make([]string, 0)
+	err := filepath.WalkDir(filepath.Join(s.Dir, blockDirName), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip directories and temp files left behind by a crash during Put
+		if !d.IsDir() && isBlockHash(d.Name()) {
+			hashes = append(hashes, d.Name())
+		}
+		return nil
+	})
+	return hashes, err
+}
+
+// HashesWithPrefix only walks the directory of the first two hex digits of prefix
+func (s *DiskBlockStorage) HashesWithPrefix(prefix string) ([]string, error) {
+	dir := filepath.Join(s.Dir, blockDirName)
+	if len(prefix) >= 2 {
+		if _, err := hex.DecodeString(prefix[0:2]); err != nil || strings.ToLower(prefix) != prefix {
+			// not the start of a block hash
+			return []string{}, nil
+		}
+		dir = filepath.Join(dir, prefix[0:2])
+	}
+	hashes := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isBlockHash(d.Name()) && strings.HasPrefix(d.Name(), prefix) {
+			hashes = append(hashes, d.Name())
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// no block in the range yet
+		return hashes, nil
+	}
+	return hashes, err
+}
+
+// Stat uses the file mtime as the time the block was last written
+func (s *DiskBlockStorage) Stat(hash string) (BlockInfo, bool, error) {
+	if !isBlockHash(hash) {
+		return BlockInfo{}, false, nil
+	}
+	info, err := os.Stat(s.blockPath(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return BlockInfo{}, false, nil
+	}
+	if err != nil {
+		return BlockInfo{}, false, err
+	}
+	return BlockInfo{Size: info.Size(), ModTime: info.ModTime()}, true, nil
+}
+
+func (s *DiskBlockStorage) Touch(hash string) error {
+	if !isBlockHash(hash) {
+		return nil
+	}
+	now := time.Now()
+	err := os.Chtimes(s.blockPath(hash), now, now)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DiskBlockStorage) Delete(hash string) error {
+	if !isBlockHash(hash) {
+		return nil
+	}
+	err := os.Remove(s.blockPath(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DiskBlockStorage) blockPath(hash string) string {
+	return filepath.Join(s.Dir, blockDirName, hash[0:2], hash[2:4], hash)
+}
+
+// This line guarantees all method for DiskBlockStorage are implemented
+var _ BlockStorage = new(DiskBlockStorage)
+
+// NewDiskBlockStorage opens (or creates) the block directory under dataDir
+// and removes temp files left behind by an interrupted Put
+func NewDiskBlockStorage(dataDir string) (*DiskBlockStorage, error) {
+	if dataDir == "" {
+		return nil, errors.New("disk block storage needs a data directory")
+	}
+	s := &DiskBlockStorage{Dir: dataDir}
+	root := filepath.Join(dataDir, blockDirName)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), tempFilePrefix) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// isBlockHash reports whether hash looks like a hex encoded sha256,
+// which also keeps hashes from clients out of path manipulation
+func isBlockHash(hash string) bool {
+	if len(hash) != 2*32 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
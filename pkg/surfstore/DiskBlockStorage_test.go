@@ -0,0 +1,76 @@
+package surfstore
+
+import (
+	"bytes"
+	context "context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestDiskBlockStoreKeepsItsBlocksAndNodeIdAcrossRestarts(t *testing.T) {
+	dataDir := t.TempDir()
+	blockStore, err := NewDiskBlockStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := map[string][]byte{}
+	for i := 0; i < 20; i++ {
+		data := []byte("block " + strconv.Itoa(i))
+		if _, err := blockStore.PutBlock(context.Background(), &Block{BlockData: data, BlockSize: int32(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		blocks[GetBlockHashString(data)] = data
+	}
+	// a Put cut short by the crash
+	leftover := filepath.Join(dataDir, blockDirName, tempFilePrefix+"1234")
+	if err := os.WriteFile(leftover, []byte("half a block"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := NewDiskBlockStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.NodeId != blockStore.NodeId || restarted.NodeId == "" {
+		t.Fatalf("node ID %q after the restart, it was %q", restarted.NodeId, blockStore.NodeId)
+	}
+	hashes, err := restarted.GetBlockHashes(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]string, 0, len(blocks))
+	for hash := range blocks {
+		want = append(want, hash)
+	}
+	sort.Strings(want)
+	sort.Strings(hashes.Hashes)
+	if !CompareBlockHashList(hashes.Hashes, want) {
+		t.Fatalf("%d blocks after the restart, %d were stored", len(hashes.Hashes), len(want))
+	}
+	for hash, data := range blocks {
+		block, err := restarted.GetBlock(context.Background(), &BlockHash{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(block.BlockData, data) || int(block.BlockSize) != len(data) {
+			t.Fatalf("block %s came back as %q", hash, block.BlockData)
+		}
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("the temp file of an interrupted Put is still there: %v", err)
+	}
+
+	// another data directory is another node
+	other, err := NewDiskBlockStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.NodeId == blockStore.NodeId {
+		t.Fatal("two data directories got the same node ID")
+	}
+}
@@ -0,0 +1,452 @@
+package surfstore
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Erasure coding: instead of keeping ReplicationFactor copies of every block, the client cuts the
+// blocks of a file into stripes of dataShards blocks and computes parityShards parity shards for
+// each stripe with Reed-Solomon, so any dataShards shards of a stripe rebuild it. The shards of a
+// stripe go to distinct block servers: the ones the ring picks for the stripe key, the hash of the
+// shard hashes, shard i on the i-th of them. A stripe survives losing parityShards block servers
+// for parityShards/dataShards extra space, where replication needs ReplicationFactor-1 times.
+//
+// Data shards are the blocks of the file as they are, parity shards are blocks of their own. The
+// stripes of a file are in its FileMetaData, which is how the MetaStore knows where their shards are.
+// Downloads read the data blocks like any other block and rebuild a stripe only when one of them
+// cannot be read.
+
+// Applies tells whether a file is erasure coded, by its name and size
+func (e *ErasureCoding) Applies(filename string, size int64) bool {
+	if e == nil || e.DataShards < 1 || e.ParityShards < 1 {
+		return false
+	}
+	if len(e.Namespaces) == 0 && e.MinFileSize == 0 {
+		return true
+	}
+	for _, namespace := range e.Namespaces {
+		if strings.HasPrefix(filename, namespace) {
+			return true
+		}
+	}
+	return e.MinFileSize > 0 && size >= e.MinFileSize
+}
+
+// ParseErasureCoding parses k+m, k data shards and m parity shards per stripe
+func ParseErasureCoding(spec string) (*ErasureCoding, error) {
+	k, m, ok := strings.Cut(spec, "+")
+	dataShards, err1 := strconv.Atoi(k)
+	parityShards, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("erasure coding %s is not k+m", spec)
+	}
+	if _, err := newReedSolomon(dataShards, parityShards); err != nil {
+		return nil, err
+	}
+	return &ErasureCoding{DataShards: int32(dataShards), ParityShards: int32(parityShards)}, nil
+}
+
+// stripeShards lists the shards of every stripe of a file: its data blocks, then its parity shards
+func stripeShards(fileMetaData *FileMetaData) ([][]string, error) {
+	shards := make([][]string, 0, len(fileMetaData.Stripes))
+	next := 0
+	for i, stripe := range fileMetaData.Stripes {
+		dataShards := len(stripe.BlockSizes)
+		if dataShards == 0 || len(stripe.ParityHashes) == 0 || dataShards+len(stripe.ParityHashes) > 256 {
+			return nil, fmt.Errorf("stripe %d of %s has %d data and %d parity shards", i, fileMetaData.Filename, dataShards, len(stripe.ParityHashes))
+		}
+		if next+dataShards > len(fileMetaData.BlockHashList) {
+			return nil, fmt.Errorf("stripes of %s go past its %d blocks", fileMetaData.Filename, len(fileMetaData.BlockHashList))
+		}
+		stripeShards := append([]string{}, fileMetaData.BlockHashList[next:next+dataShards]...)
+		shards = append(shards, append(stripeShards, stripe.ParityHashes...))
+		next += dataShards
+	}
+	if len(fileMetaData.Stripes) > 0 && next != len(fileMetaData.BlockHashList) {
+		return nil, fmt.Errorf("stripes of %s cover %d of its %d blocks", fileMetaData.Filename, next, len(fileMetaData.BlockHashList))
+	}
+	return shards, nil
+}
+
+// stripeKey is where the ring places a stripe
+func stripeKey(shards []string) string {
+	return GetBlockHashString([]byte(strings.Join(shards, HASH_DELIMITER)))
+}
+
+// stripeServers returns the server of every shard of the stripe key. The servers are distinct
+// when the ring has enough of them, otherwise they wrap around
+func (ring *blockStoreRing) stripeServers(key string, shards int) []string {
+	distinct := ring.responsibleServers(key, shards)
+	servers := make([]string, shards)
+	for i := range servers {
+		servers[i] = distinct[i%len(distinct)]
+	}
+	return servers
+}
+
+// stripeShard is shard index of the stripe key, which has shards shards
+type stripeShard struct {
+	key    string
+	index  int
+	shards int
+}
+
+// shardIndex counts the references to every block, so the MetaStore knows where a block is
+type shardIndex struct {
+	// block hash -> the stripe shards it is -> how many files
+	shards map[string]map[stripeShard]int
+	// block hash -> how many replicated files reference it
+	replicated map[string]int
+}
+
+func newShardIndex() *shardIndex {
+	return &shardIndex{shards: map[string]map[stripeShard]int{}, replicated: map[string]int{}}
+}
+
+// add counts the blocks of a file once more, or once less with delta -1
+func (idx *shardIndex) add(fileMetaData *FileMetaData, delta int) {
+	shards, err := stripeShards(fileMetaData)
+	if err != nil {
+		// UpdateFile turns these away, a bad file in a log is placed like a replicated one
+		log.Printf("Placing %s as a replicated file: %v", fileMetaData.Filename, err)
+		shards = nil
+	}
+	if len(shards) == 0 {
+		seen := map[string]bool{}
+		for _, hash := range fileMetaData.BlockHashList {
+			if hash == TOMBSTONE_HASHVALUE || hash == EMPTYFILE_HASHVALUE || seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			if idx.replicated[hash] += delta; idx.replicated[hash] <= 0 {
+				delete(idx.replicated, hash)
+			}
+		}
+		return
+	}
+	for _, stripe := range shards {
+		key := stripeKey(stripe)
+		for i, hash := range stripe {
+			shard := stripeShard{key: key, index: i, shards: len(stripe)}
+			if idx.shards[hash] == nil {
+				idx.shards[hash] = map[stripeShard]int{}
+			}
+			if idx.shards[hash][shard] += delta; idx.shards[hash][shard] <= 0 {
+				delete(idx.shards[hash], shard)
+				if len(idx.shards[hash]) == 0 {
+					delete(idx.shards, hash)
+				}
+			}
+		}
+	}
+}
+
+// blockServers returns the servers a block is on: its ReplicationFactor replicas if a replicated
+// file references it or no file does yet, and the server of every stripe shard it is.
+// A block shared by a replicated and an erasure coded file is on both
+func (m *MetaStore) blockServers(ring *blockStoreRing, hash string) []string {
+	m.RWMutex.RLock()
+	replicated := m.shards == nil || m.shards.replicated[hash] > 0
+	shards := make([]stripeShard, 0)
+	if m.shards != nil {
+		for shard := range m.shards.shards[hash] {
+			shards = append(shards, shard)
+		}
+	}
+	m.RWMutex.RUnlock()
+
+	servers := make([]string, 0)
+	if replicated || len(shards) == 0 {
+		servers = append(servers, ring.responsibleServers(hash, m.ReplicationFactor)...)
+	}
+	// in the same order every time, migrations compare the servers of the old and the new ring
+	sort.Slice(shards, func(i, j int) bool {
+		if shards[i].key != shards[j].key {
+			return shards[i].key < shards[j].key
+		}
+		return shards[i].index < shards[j].index
+	})
+	for _, shard := range shards {
+		if server := ring.stripeServers(shard.key, shard.shards)[shard.index]; !containsString(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// stripeShardHashes lists every block that is a shard of a stripe
+func (m *MetaStore) stripeShardHashes() []string {
+	m.RWMutex.RLock()
+	defer m.RWMutex.RUnlock()
+	hashes := make([]string, 0)
+	if m.shards == nil {
+		return hashes
+	}
+	for hash := range m.shards.shards {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// uploadStripes erasure codes a file and puts every shard on its block server, it returns the stripes
+// of the file. The parity of at most ERASURE_UPLOAD_BUFFER bytes of shards is kept in memory at once
+func uploadStripes(client RPCClient, file *os.File, blockHashList []string, blockStores *BlockStoreAddrs, uploaded syncedBlocks) []*Stripe {
+	erasureCoding := blockStores.ErasureCoding
+	ring := newBlockStoreRing(blockStores)
+	stripes := make([]*Stripe, 0, (len(blockHashList)+int(erasureCoding.DataShards)-1)/int(erasureCoding.DataShards))
+	// server address -> the shards to put on it
+	pending := map[string][]*Block{}
+	pendingBytes := 0
+	for start := 0; start < len(blockHashList); start += int(erasureCoding.DataShards) {
+		end := min(start+int(erasureCoding.DataShards), len(blockHashList))
+		rs, err := newReedSolomon(end-start, int(erasureCoding.ParityShards))
+		if err != nil {
+			log.Fatalf("Cannot erasure code %s: %v", file.Name(), err)
+		}
+		blocks := make([]*Block, 0, end-start+int(erasureCoding.ParityShards))
+		stripe := &Stripe{BlockSizes: make([]int32, 0, end-start)}
+		shardSize := 0
+		for i := start; i < end; i++ {
+			block, err := readBlock(file, int64(i), client.BlockSize)
+			if err != nil {
+				log.Fatalf("Cannot read block %d of %s: %v", i, file.Name(), err)
+			}
+			blocks = append(blocks, block)
+			stripe.BlockSizes = append(stripe.BlockSizes, block.BlockSize)
+			shardSize = max(shardSize, len(block.BlockData))
+		}
+		data := make([][]byte, len(blocks))
+		for i, block := range blocks {
+			data[i] = padShard(block.BlockData, shardSize)
+		}
+		parity, err := rs.Encode(data)
+		if err != nil {
+			log.Fatalf("Cannot erasure code %s: %v", file.Name(), err)
+		}
+		for _, shard := range parity {
+			blocks = append(blocks, &Block{BlockData: shard, BlockSize: int32(len(shard))})
+			stripe.ParityHashes = append(stripe.ParityHashes, GetBlockHashString(shard))
+		}
+		stripes = append(stripes, stripe)
+
+		shards := append(append([]string{}, blockHashList[start:end]...), stripe.ParityHashes...)
+		for i, server := range ring.stripeServers(stripeKey(shards), len(shards)) {
+			pending[server] = append(pending[server], blocks[i])
+			pendingBytes += len(blocks[i].BlockData)
+		}
+		if pendingBytes >= ERASURE_UPLOAD_BUFFER || end == len(blockHashList) {
+			putShards(client, pending, uploaded)
+			pending, pendingBytes = map[string][]*Block{}, 0
+		}
+	}
+	return stripes
+}
+
+// putShards puts the shards each block server is missing on it, on client.Jobs block servers at once
+func putShards(client RPCClient, pending map[string][]*Block, uploaded syncedBlocks) {
+	servers := make([]string, 0, len(pending))
+	for serverAddr := range pending {
+		servers = append(servers, serverAddr)
+	}
+	sort.Strings(servers)
+	forEachServer(client, servers, uploaded, func(serverAddr string) {
+		blocks := pending[serverAddr]
+		hashToBlock := map[string]*Block{}
+		hashes := make([]string, 0, len(blocks))
+		for _, block := range blocks {
+			block.Hash = GetBlockHashString(block.BlockData)
+			hashToBlock[block.Hash] = block
+			hashes = append(hashes, block.Hash)
+		}
+		hashes, err := missingBlocks(client, serverAddr, hashes, uploaded)
+		if err != nil {
+			log.Fatalf("Error while putting shards to the server %s: %v", serverAddr, err)
+		}
+		if len(hashes) == 0 {
+			return
+		}
+		next := 0
+		var success bool
+		err = client.PutBlocks(serverAddr, func() (*Block, error) {
+			if next == len(hashes) {
+				return nil, io.EOF
+			}
+			next++
+			return hashToBlock[hashes[next-1]], nil
+		}, &success)
+		if err != nil || !success {
+			log.Fatalf("Error while putting shards to the server %s: %v", serverAddr, err)
+		}
+		for _, hash := range hashes {
+			uploaded[serverAddr][hash] = true
+		}
+	})
+}
+
+// padShard returns data padded with zeros to size bytes
+func padShard(data []byte, size int) []byte {
+	if len(data) == size {
+		return data
+	}
+	padded := make([]byte, size)
+	copy(padded, data)
+	return padded
+}
+
+// downloadStripes downloads an erasure coded file in order. The data blocks are streamed like the
+// blocks of any other file, a stripe with a block that cannot be read is rebuilt from its other shards
+func downloadStripes(client RPCClient, fileMetaData *FileMetaData, blockStores *BlockStoreAddrs, write func(*Block) error) error {
+	shards, err := stripeShards(fileMetaData)
+	if err != nil {
+		return err
+	}
+	hashes := append([]string{}, fileMetaData.BlockHashList...)
+	for _, stripe := range fileMetaData.Stripes {
+		hashes = append(hashes, stripe.ParityHashes...)
+	}
+	replicas := blockReplicas(getBlockStoreMap(client, hashes), blockStores)
+
+	// block index -> its stripe
+	stripeOf := make([]int, 0, len(fileMetaData.BlockHashList))
+	for s, stripe := range fileMetaData.Stripes {
+		for range stripe.BlockSizes {
+			stripeOf = append(stripeOf, s)
+		}
+	}
+	next := 0
+	for next < len(fileMetaData.BlockHashList) {
+		var writeErr error
+		err := downloadBlocks(client, fileMetaData.BlockHashList[next:], replicas, func(block *Block) error {
+			if writeErr = write(block); writeErr != nil {
+				return writeErr
+			}
+			next++
+			return nil
+		})
+		if err == nil || writeErr != nil {
+			return err
+		}
+		s := stripeOf[next]
+		log.Printf("Rebuilding stripe %d of %s: %v", s, fileMetaData.Filename, err)
+		blocks, err := rebuildStripe(client, shards[s], fileMetaData.Stripes[s].BlockSizes, replicas)
+		if err != nil {
+			return fmt.Errorf("cannot rebuild stripe %d: %w", s, err)
+		}
+		// the blocks of the stripe before next are written already
+		first := next
+		for first > 0 && stripeOf[first-1] == s {
+			first--
+		}
+		for ; next < len(stripeOf) && stripeOf[next] == s; next++ {
+			if err := write(blocks[next-first]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rebuildStripe gets the first shards of a stripe it can read until it has as many as data blocks,
+// and rebuilds the data blocks out of them. A rebuilt data block, and a parity shard it computes
+// again, go back to the block servers that answered they do not have it
+func rebuildStripe(client RPCClient, shards []string, blockSizes []int32, replicas map[string][]string) ([]*Block, error) {
+	dataShards := len(blockSizes)
+	rs, err := newReedSolomon(dataShards, len(shards)-dataShards)
+	if err != nil {
+		return nil, err
+	}
+	shardSize := 0
+	for _, blockSize := range blockSizes {
+		shardSize = max(shardSize, int(blockSize))
+	}
+	data := make([][]byte, len(shards))
+	lacking := make([][]string, len(shards))
+	found := 0
+	for i, hash := range shards {
+		if found == dataShards {
+			break
+		}
+		var shard []byte
+		shard, lacking[i] = fetchShard(client, hash, replicas[hash])
+		if shard != nil {
+			data[i] = padShard(shard, shardSize)
+			found++
+		}
+	}
+	if err := rs.Reconstruct(data); err != nil {
+		return nil, err
+	}
+	blocks := make([]*Block, dataShards)
+	for i := range blocks {
+		if int(blockSizes[i]) > len(data[i]) {
+			return nil, fmt.Errorf("block %s is longer than the shards", shards[i])
+		}
+		blockData := data[i][:blockSizes[i]]
+		if GetBlockHashString(blockData) != shards[i] {
+			return nil, fmt.Errorf("rebuilt block %s does not match its hash", shards[i])
+		}
+		blocks[i] = &Block{BlockData: blockData, BlockSize: blockSizes[i]}
+		readRepair(client, shards[i], blocks[i], lacking[i])
+	}
+	repairParity(client, rs, shards, data[:dataShards], lacking[dataShards:])
+	return blocks, nil
+}
+
+// repairParity computes the parity shards of a rebuilt stripe again and writes those that are lacking
+// back, as readRepair does for data blocks. It only logs a failure, the download has its blocks
+func repairParity(client RPCClient, rs *reedSolomon, shards []string, data [][]byte, lacking [][]string) {
+	repair := false
+	for _, servers := range lacking {
+		repair = repair || len(servers) > 0
+	}
+	if !repair {
+		return
+	}
+	parity, err := rs.Encode(data)
+	if err != nil {
+		log.Printf("Cannot compute the parity shards of stripe %s again: %v", stripeKey(shards), err)
+		return
+	}
+	for i, shard := range parity {
+		if len(lacking[i]) == 0 {
+			continue
+		}
+		hash := shards[len(data)+i]
+		if GetBlockHashString(shard) != hash {
+			log.Printf("Parity shard %s computed again does not match its hash", hash)
+			continue
+		}
+		readRepair(client, hash, &Block{BlockData: shard, BlockSize: int32(len(shard))}, lacking[i])
+	}
+}
+
+// fetchShard gets a shard from the first of servers that has it, checking its hash. It also returns
+// the servers that answered they do not have it
+func fetchShard(client RPCClient, hash string, servers []string) ([]byte, []string) {
+	lacking := make([]string, 0)
+	for _, serverAddr := range servers {
+		block := &Block{}
+		if err := client.GetBlock(hash, serverAddr, block); err != nil {
+			log.Printf("Error while getting shard %s from the server %s: %v", hash, serverAddr, err)
+			if status.Code(err) == codes.NotFound {
+				lacking = append(lacking, serverAddr)
+			}
+			continue
+		}
+		if GetBlockHashString(block.BlockData) != hash {
+			log.Printf("The server %s sent a shard that does not match %s", serverAddr, hash)
+			continue
+		}
+		return block.BlockData, lacking
+	}
+	return nil, lacking
+}
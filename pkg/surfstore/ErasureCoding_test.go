@@ -0,0 +1,181 @@
+package surfstore
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestErasureCodedFilesSurviveLosingParityShardsWorthOfServers(t *testing.T) {
+	erasureCoding := &ErasureCoding{DataShards: 4, ParityShards: 2, MinFileSize: 8 * 1024}
+	blockStores := startTestBlockStores(t, int(erasureCoding.DataShards+erasureCoding.ParityShards))
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ErasureCoding = erasureCoding
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{
+		"big.bin":    40 * 1024,
+		"odd.bin":    13*1024 + 7, // a last stripe of fewer data shards, the last one short
+		"border.bin": 8 * 1024,
+	})
+	ClientSync(uploader)
+
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := uploader.GetFileInfoMap(&fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		if len(fileInfoMap[name].Stripes) == 0 {
+			t.Fatalf("%s is above -ec-min-size but was not erasure coded", name)
+		}
+	}
+
+	// stop the servers of the first two data shards of the first stripe of big.bin, with 6 servers
+	// every shard of a stripe is on a server of its own
+	shards, err := stripeShards(fileInfoMap["big.bin"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := metaStore.blockStores().stripeServers(stripeKey(shards[0]), len(shards[0]))
+	stopped := map[string]bool{servers[0]: true, servers[1]: true}
+	if len(stopped) != int(erasureCoding.ParityShards) {
+		t.Fatalf("the data shards of a stripe share a server: %v", servers)
+	}
+	for _, blockStore := range blockStores {
+		if stopped[blockStore.Addr] {
+			blockStore.Stop()
+		}
+	}
+
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+}
+
+func TestReedSolomonRebuildsWithParityInEveryPosition(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, shape := range [][2]int{{1, 1}, {1, 3}, {3, 2}, {4, 2}, {5, 3}} {
+		dataShards, parityShards := shape[0], shape[1]
+		rs, err := newReedSolomon(dataShards, parityShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([][]byte, dataShards)
+		for i := range data {
+			data[i] = make([]byte, 64)
+			random.Read(data[i])
+		}
+		parity, err := rs.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parity) != parityShards {
+			t.Fatalf("%d+%d: %d parity shards", dataShards, parityShards, len(parity))
+		}
+
+		// lose every set of parityShards shards, so the parity shards left stand in for data shards
+		// at every position
+		total := dataShards + parityShards
+		for lost := 0; lost < 1<<total; lost++ {
+			if bitCount(lost) != parityShards {
+				continue
+			}
+			shards := make([][]byte, total)
+			for i := range shards {
+				if lost&(1<<i) != 0 {
+					continue
+				}
+				if i < dataShards {
+					shards[i] = append([]byte{}, data[i]...)
+				} else {
+					shards[i] = append([]byte{}, parity[i-dataShards]...)
+				}
+			}
+			if err := rs.Reconstruct(shards); err != nil {
+				t.Fatalf("%d+%d without shards %b: %v", dataShards, parityShards, lost, err)
+			}
+			for i := range data {
+				if !bytes.Equal(shards[i], data[i]) {
+					t.Fatalf("%d+%d without shards %b rebuilt data shard %d wrong", dataShards, parityShards, lost, i)
+				}
+			}
+		}
+
+		// one shard fewer than dataShards cannot rebuild the data
+		shards := make([][]byte, total)
+		for i := 1; i < dataShards; i++ {
+			shards[i] = data[i]
+		}
+		if err := rs.Reconstruct(shards); err == nil {
+			t.Fatalf("%d+%d rebuilt the data out of %d shards", dataShards, parityShards, dataShards-1)
+		}
+	}
+}
+
+func bitCount(n int) int {
+	count := 0
+	for ; n > 0; n &= n - 1 {
+		count++
+	}
+	return count
+}
+
+func TestParseErasureCoding(t *testing.T) {
+	for spec, ok := range map[string]bool{"4+2": true, "1+1": true, "4": false, "4+": false, "+2": false, "a+b": false, "0+2": false, "4+0": false} {
+		erasureCoding, err := ParseErasureCoding(spec)
+		if (err == nil) != ok {
+			t.Fatalf("parsing %s: %v", spec, err)
+		}
+		if ok && fmt.Sprintf("%d+%d", erasureCoding.DataShards, erasureCoding.ParityShards) != spec {
+			t.Fatalf("%s parsed as %d+%d", spec, erasureCoding.DataShards, erasureCoding.ParityShards)
+		}
+	}
+}
+
+func TestRebuiltStripeWritesLackingShardsBack(t *testing.T) {
+	erasureCoding := &ErasureCoding{DataShards: 3, ParityShards: 2}
+	blockStores := startTestBlockStores(t, int(erasureCoding.DataShards+erasureCoding.ParityShards))
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ErasureCoding = erasureCoding
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{"a.bin": 3 * 1024})
+	ClientSync(uploader)
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := uploader.GetFileInfoMap(&fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	shards, err := stripeShards(fileInfoMap["a.bin"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lose the first data shard, so the stripe is rebuilt, and the first parity shard, which the
+	// rebuild reads next after the other data shards
+	lost := []string{shards[0][0], shards[0][erasureCoding.DataShards]}
+	for _, blockStore := range blockStores {
+		for _, hash := range lost {
+			if err := blockStore.BlockStore.Storage.Delete(hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+	servers := metaStore.blockStores().stripeServers(stripeKey(shards[0]), len(shards[0]))
+	for _, blockStore := range blockStores {
+		for i, hash := range shards[0] {
+			if servers[i] != blockStore.Addr {
+				continue
+			}
+			if has, _ := blockStore.BlockStore.Storage.Has(hash); !has {
+				t.Fatalf("shard %d of the rebuilt stripe was not written back to its server %s", i, blockStore.Addr)
+			}
+		}
+	}
+}
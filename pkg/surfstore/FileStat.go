@@ -0,0 +1,23 @@
+package surfstore
+
+import (
+	"os"
+)
+
+// FileStat is the stat data of a local file when the client last hashed it. A file that still has
+// the same size, modification time and inode is taken to still have the blocks it had then,
+// and is not read again
+type FileStat struct {
+	Size int64
+	// nanoseconds since the epoch
+	ModTime int64
+	Inode   uint64
+}
+
+func statOf(info os.FileInfo) FileStat {
+	return FileStat{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Inode:   fileInode(info),
+	}
+}
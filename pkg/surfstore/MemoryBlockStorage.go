@@ -0,0 +1,98 @@
+package surfstore
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBlockStorage keeps every block in a map, everything is lost when the server stops
+type MemoryBlockStorage struct {
+	// BlockMap is a map that stores the block hash as the key and the block as the value
+	BlockMap map[string]*Block
+	// PutTime is when each block was last written or touched, garbage collection keeps recent blocks
+	PutTime map[string]time.Time
+	RWMutex sync.RWMutex
+}
+
+func (s *MemoryBlockStorage) Get(hash string) (*Block, bool, error) {
+	// RWMutex: when multiple clients try to get the block, map could be modified
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	block, exists := s.BlockMap[hash]
+	return block, exists, nil
+}
+
+func (s *MemoryBlockStorage) Put(hash string, block *Block) error {
+	s.RWMutex.Lock()
+	defer s.RWMutex.Unlock()
+	s.BlockMap[hash] = block
+	s.PutTime[hash] = time.Now()
+	return nil
+}
+
+func (s *MemoryBlockStorage) Has(hash string) (bool, error) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	_, exists := s.BlockMap[hash]
+	return exists, nil
+}
+
+func (s *MemoryBlockStorage) Hashes() ([]string, error) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	hashes := make([]string, 0, len(s.BlockMap))
+	for hash := range s.BlockMap { // hash: key(block hash)
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (s *MemoryBlockStorage) HashesWithPrefix(prefix string) ([]string, error) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	hashes := make([]string, 0)
+	for hash := range s.BlockMap {
+		if strings.HasPrefix(hash, prefix) {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+func (s *MemoryBlockStorage) Stat(hash string) (BlockInfo, bool, error) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	block, exists := s.BlockMap[hash]
+	if !exists {
+		return BlockInfo{}, false, nil
+	}
+	return BlockInfo{Size: int64(len(block.BlockData)), ModTime: s.PutTime[hash]}, true, nil
+}
+
+func (s *MemoryBlockStorage) Touch(hash string) error {
+	s.RWMutex.Lock()
+	defer s.RWMutex.Unlock()
+	if _, exists := s.BlockMap[hash]; exists {
+		s.PutTime[hash] = time.Now()
+	}
+	return nil
+}
+
+func (s *MemoryBlockStorage) Delete(hash string) error {
+	s.RWMutex.Lock()
+	defer s.RWMutex.Unlock()
+	delete(s.BlockMap, hash)
+	delete(s.PutTime, hash)
+	return nil
+}
+
+// This line guarantees all method for MemoryBlockStorage are implemented
+var _ BlockStorage = new(MemoryBlockStorage)
+
+func NewMemoryBlockStorage() *MemoryBlockStorage {
+	return &MemoryBlockStorage{
+		BlockMap: map[string]*Block{},
+		PutTime:  map[string]time.Time{},
+	}
+}
@@ -2,8 +2,16 @@ package surfstore
 
 import (
 	context "context"
+	"fmt"
+	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -13,54 +21,80 @@ type MetaStore struct {
 	FileMetaMap map[string]*FileMetaData
 	RWMutex     sync.RWMutex
 	//BlockStoreAddr string
-	BlockStoreAddrs    []string
+	BlockStoreAddrs []string
+	// BlockStores is the configuration of the ring: the BlockStoreAddrs, their weights, virtual nodes
+	// and placement strategy
+	BlockStores *BlockStoreAddrs
+	// ConsistentHashRing places blocks by the node ID of the block servers, see BlockStores.NodeIds.
+	// nil when BlockStores.Placement is not consistent hashing
 	ConsistentHashRing *ConsistentHashRing
+	// ReplicationFactor is how many block servers keep a copy of each block
+	ReplicationFactor int
+	// ErasureCoding is which files clients erasure code and how, nil if none
+	ErasureCoding *ErasureCoding
+	// Log keeps FileMetaMap across restarts, nil if the MetaStore only lives in memory
+	Log *MetaStoreLog
+	// migrationMu lets one AddBlockStore or RemoveBlockStore run at a time
+	migrationMu sync.Mutex
+	// ring is what lookups use, swapped as a whole so they never wait for UpdateFile
+	// or see the configuration of one ring with another ring
+	ring atomic.Pointer[blockStoreRing]
+	// health is what the probes of the block servers found out, nil until StartHealthChecks
+	health atomic.Pointer[blockStoreHealth]
+	// shards knows which blocks are stripe shards, guarded by RWMutex
+	shards *shardIndex
 	UnimplementedMetaStoreServer
 }
 
 func (m *MetaStore) GetFileInfoMap(ctx context.Context, _ *emptypb.Empty) (*FileInfoMap, error) {
 	// Retrieves the server's FileInfoMap
 	// map<string, FileMetaData> fileInfoMap
-	// string: used to get the member of FileInfoMap struct, I think.
-	//m.RWMutex.RLock()
-	//defer m.RWMutex.RUnlock()
-	//type FileInfoMap struct {
-	//	state         protoimpl.MessageState
-	//	sizeCache     protoimpl.SizeCache
-	//	unknownFields protoimpl.UnknownFields
-	//
-	//	FileInfoMap map[string]*FileMetaData `protobuf:"bytes,1,rep,name=fileInfoMap,proto3" json:"fileInfoMap,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	//}
-	//fileInfoMap := &FileInfoMap{
-	//	FileInfoMap: make(map[string]*FileMetaData),
-	//}
-	//m.RWMutex.RLock()
-	//for k, v := range m.FileMetaMap {
-	//	fileInfoMap.FileInfoMap[k] = v
-	//}
-	//m.RWMutex.RUnlock()
-	return &FileInfoMap{FileInfoMap: m.FileMetaMap}, nil
+	// copy the map under the lock, UpdateFile may change it while grpc is marshaling the reply
+	m.RWMutex.RLock()
+	defer m.RWMutex.RUnlock()
+	fileInfoMap := &FileInfoMap{
+		FileInfoMap: make(map[string]*FileMetaData),
+	}
+	for k, v := range m.FileMetaMap {
+		fileInfoMap.FileInfoMap[k] = v
+	}
+	return fileInfoMap, nil
 }
 
 func (m *MetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
-	m.RWMutex.RLock()
+	m.RWMutex.Lock()
+	defer m.RWMutex.Unlock()
 	//message FileMetaData {
 	//    string filename = 1;
 	//    int32 version = 2;
 	//    repeated string blockHashList = 3;
 	//}
-	_, exists := m.FileMetaMap[fileMetaData.Filename]
+	cur, exists := m.FileMetaMap[fileMetaData.Filename]
+	if exists && fileMetaData.Version <= cur.Version {
+		return &Version{Version: -1}, nil
+	}
+	if _, err := stripeShards(fileMetaData); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// the update is only accepted once it is in the log
+	if m.Log != nil {
+		if err := m.Log.Append(fileMetaData); err != nil {
+			return nil, err
+		}
+	}
+	m.FileMetaMap[fileMetaData.Filename] = fileMetaData
+	if m.shards == nil {
+		m.shards = newShardIndex()
+	}
 	if exists {
-		curVersion := m.FileMetaMap[fileMetaData.Filename].Version
-		if fileMetaData.Version > curVersion {
-			m.FileMetaMap[fileMetaData.Filename] = fileMetaData
-		} else {
-			return &Version{Version: -1}, nil
+		m.shards.add(cur, -1)
+	}
+	m.shards.add(fileMetaData, 1)
+	if m.Log != nil && m.Log.ShouldSnapshot() {
+		if err := m.Log.Snapshot(m.FileMetaMap); err != nil {
+			log.Printf("Error while taking a metadata snapshot: %v", err)
 		}
-	} else {
-		m.FileMetaMap[fileMetaData.Filename] = fileMetaData
 	}
-	m.RWMutex.RUnlock()
 	return &Version{Version: fileMetaData.Version}, nil
 }
 
@@ -75,20 +109,29 @@ func (m *MetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData)
 //}
 
 // Given a list of block hashes,
-// find out which block server they belong to.
-// Returns a mapping from block server address to block hashes.
+// find out which block servers they belong to.
+// Returns a mapping from block server address to block hashes,
+// a hash is listed under every one of its servers that is not down (see blockServers),
+// and the zone of every listed block server that has one.
+// The map keeps no order of the servers of a hash, clients put them back in ring order (blockReplicas).
 func (m *MetaStore) GetBlockStoreMap(ctx context.Context, blockHashesIn *BlockHashes) (*BlockStoreMap, error) {
 	//panic("todo")
 	// BlockStoreMap map[string]*BlockHashes
+	ring := m.blockStores()
 	blockStoreMap := make(map[string]*BlockHashes)
+	zones := make(map[string]string)
 	for _, blockHash := range blockHashesIn.Hashes {
-		blockStoreAddr := m.ConsistentHashRing.GetResponsibleServer(blockHash)
-		if _, exists := blockStoreMap[blockStoreAddr]; !exists {
-			blockStoreMap[blockStoreAddr] = &BlockHashes{Hashes: []string{}}
+		for _, blockStoreAddr := range m.liveReplicas(m.blockServers(ring, blockHash)) {
+			if _, exists := blockStoreMap[blockStoreAddr]; !exists {
+				blockStoreMap[blockStoreAddr] = &BlockHashes{Hashes: []string{}}
+				if zone, ok := ring.config.Zones[blockStoreAddr]; ok {
+					zones[blockStoreAddr] = zone
+				}
+			}
+			blockStoreMap[blockStoreAddr].Hashes = append(blockStoreMap[blockStoreAddr].Hashes, blockHash)
 		}
-		blockStoreMap[blockStoreAddr].Hashes = append(blockStoreMap[blockStoreAddr].Hashes, blockHash)
 	}
-	return &BlockStoreMap{BlockStoreMap: blockStoreMap}, nil
+	return &BlockStoreMap{BlockStoreMap: blockStoreMap, Zones: zones}, nil
 }
 
 func (m *MetaStore) GetBlockStoreAddrs(ctx context.Context, _ *emptypb.Empty) (*BlockStoreAddrs, error) {
@@ -96,7 +139,97 @@ func (m *MetaStore) GetBlockStoreAddrs(ctx context.Context, _ *emptypb.Empty) (*
 	//message BlockStoreAddrs {
 	//	repeated string blockStoreAddrs = 1;
 	//}
-	return &BlockStoreAddrs{BlockStoreAddrs: m.BlockStoreAddrs}, nil
+	// the weights, virtual nodes, node IDs and zones come along, so clients can check the balance of the ring,
+	// and the replication factor and erasure coding, which are not part of the saved configuration
+	blockStores := proto.Clone(m.blockStores().config).(*BlockStoreAddrs)
+	blockStores.Replication = int32(m.ReplicationFactor)
+	blockStores.ErasureCoding = m.ErasureCoding
+	return blockStores, nil
+}
+
+// Mark and sweep: every hash referenced by the latest version of a file is live,
+// and each block server deletes the blocks that are not, or that are live but no longer its to
+// hold, such as the old copies of the blocks a migration moved. Such a copy is only deleted once
+// the replicas of the block have it. Deleted files (tombstones)
+// and empty files reference no block. A block server that fails is reported,
+// the others are still swept
+func (m *MetaStore) CollectGarbage(ctx context.Context, gc *GarbageCollection) (*GarbageCollectionReport, error) {
+	// the ring must not change between placing the live blocks and sweeping them
+	m.migrationMu.Lock()
+	defer m.migrationMu.Unlock()
+	ring := m.blockStores()
+	live := map[string]bool{}
+	// block server -> the live blocks it keeps
+	keep := map[string][]string{}
+	for _, hash := range m.liveHashes() {
+		live[hash] = true
+		for _, blockStoreAddr := range m.blockServers(ring, hash) {
+			keep[blockStoreAddr] = append(keep[blockStoreAddr], hash)
+		}
+	}
+	gracePeriod := time.Duration(gc.GracePeriodSeconds) * time.Second
+	blockStores := ring.config
+	replicasOf := func(hash string) []string { return m.blockServers(ring, hash) }
+
+	client := &RPCClient{}
+	total := &GarbageCollectionReport{}
+	failed := make([]string, 0)
+	for _, blockStoreAddr := range blockStores.BlockStoreAddrs {
+		// a live block held by a server that is not one of its replicas, uploaded there with an old
+		// block map or left behind by a migration, may be the only copy
+		held, err := listBlocks(client, blockStoreAddr)
+		if err != nil {
+			log.Printf("Error while listing the blocks on %s: %v", blockStoreAddr, err)
+			failed = append(failed, blockStoreAddr)
+			continue
+		}
+		strays := make([]string, 0)
+		for _, hash := range held {
+			if live[hash] && !containsString(replicasOf(hash), blockStoreAddr) {
+				strays = append(strays, hash)
+			}
+		}
+		kept := append(keep[blockStoreAddr], rehomeBlocks(client, blockStoreAddr, strays, replicasOf)...)
+
+		report := &GarbageCollectionReport{}
+		if err := client.SweepBlocks(kept, gracePeriod, blockStoreAddr, report); err != nil {
+			log.Printf("Error while sweeping blocks on %s: %v", blockStoreAddr, err)
+			failed = append(failed, blockStoreAddr)
+			continue
+		}
+		total.BlocksScanned += report.BlocksScanned
+		total.BlocksDeleted += report.BlocksDeleted
+		total.BytesReclaimed += report.BytesReclaimed
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("garbage collection failed on %s", strings.Join(failed, CONFIG_DELIMITER))
+	}
+	return total, nil
+}
+
+// liveHashes lists every block hash referenced by a file, parity shards included, once
+func (m *MetaStore) liveHashes() []string {
+	m.RWMutex.RLock()
+	defer m.RWMutex.RUnlock()
+	seen := make(map[string]bool)
+	hashes := make([]string, 0)
+	for _, fileMetaData := range m.FileMetaMap {
+		fileHashes := fileMetaData.BlockHashList
+		if len(fileMetaData.Stripes) > 0 {
+			fileHashes = append([]string{}, fileHashes...)
+			for _, stripe := range fileMetaData.Stripes {
+				fileHashes = append(fileHashes, stripe.ParityHashes...)
+			}
+		}
+		for _, hash := range fileHashes {
+			if hash == TOMBSTONE_HASHVALUE || hash == EMPTYFILE_HASHVALUE || seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
 }
 
 // This line guarantees all method for MetaStore are implemented
@@ -104,10 +237,48 @@ var _ MetaStoreInterface = new(MetaStore)
 
 // func NewMetaStore(blockStoreAddr string) *MetaStore {
 func NewMetaStore(blockStoreAddrs []string) *MetaStore {
-	return &MetaStore{
+	return NewWeightedMetaStore(&BlockStoreAddrs{BlockStoreAddrs: blockStoreAddrs, VirtualNodes: int32(DEFAULT_VIRTUAL_NODES), Placement: DEFAULT_PLACEMENT})
+}
+
+// NewWeightedMetaStore creates a MetaStore whose ring gives the block servers their weights and virtual nodes,
+// and places blocks with the strategy in blockStores.Placement
+func NewWeightedMetaStore(blockStores *BlockStoreAddrs) *MetaStore {
+	ring := newBlockStoreRing(blockStores)
+	consistentHashRing, _ := ring.placement.(*ConsistentHashRing)
+	metaStore := &MetaStore{
 		FileMetaMap: map[string]*FileMetaData{},
 		//BlockStoreAddr: blockStoreAddr,
-		BlockStoreAddrs:    blockStoreAddrs,
-		ConsistentHashRing: NewConsistentHashRing(blockStoreAddrs),
+		BlockStoreAddrs:    blockStores.BlockStoreAddrs,
+		BlockStores:        blockStores,
+		ConsistentHashRing: consistentHashRing,
+		ReplicationFactor:  DEFAULT_REPLICATION_FACTOR,
+		shards:             newShardIndex(),
+	}
+	metaStore.ring.Store(ring)
+	return metaStore
+}
+
+// NewPersistentMetaStore creates a MetaStore that logs every update under dataDir
+// and recovers its FileMetaMap from there, snapshotting every snapshotEvery updates
+func NewPersistentMetaStore(blockStores *BlockStoreAddrs, dataDir string, snapshotEvery int) (*MetaStore, error) {
+	metaLog, fileMetaMap, err := OpenMetaStoreLog(dataDir, snapshotEvery)
+	if err != nil {
+		return nil, err
+	}
+	// a ring changed by AddBlockStore or RemoveBlockStore wins over the command line
+	savedBlockStores, err := metaLog.BlockStores()
+	if err != nil {
+		metaLog.Close()
+		return nil, err
+	}
+	if savedBlockStores != nil {
+		blockStores = savedBlockStores
+	}
+	metaStore := NewWeightedMetaStore(blockStores)
+	metaStore.FileMetaMap = fileMetaMap
+	for _, fileMetaData := range fileMetaMap {
+		metaStore.shards.add(fileMetaData, 1)
 	}
+	metaStore.Log = metaLog
+	return metaStore, nil
 }
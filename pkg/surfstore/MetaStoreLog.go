@@ -0,0 +1,134 @@
+package surfstore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MetaStoreLog makes the FileMetaMap of a MetaStore durable.
+// Every accepted UpdateFile is appended to a write-ahead log, and every SnapshotEvery
+// records the whole map is written to a snapshot and the log starts over.
+// On startup the snapshot is loaded and the log is replayed on top of it.
+type MetaStoreLog struct {
+	Dir           string
+	SnapshotEvery int
+	wal           *WriteAheadLog
+	walRecords    int
+}
+
+const metaWalFilename string = "meta.wal"
+const metaSnapshotFilename string = "meta.snapshot"
+const metaBlockStoresFilename string = "meta.blockstores"
+
+// OpenMetaStoreLog opens the log in dir and returns the FileMetaMap it describes
+func OpenMetaStoreLog(dir string, snapshotEvery int) (*MetaStoreLog, map[string]*FileMetaData, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	fileMetaMap := make(map[string]*FileMetaData)
+
+	// 1. the snapshot
+	data, err := os.ReadFile(filepath.Join(dir, metaSnapshotFilename))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	if err == nil {
+		snapshot := &FileInfoMap{}
+		if err := proto.Unmarshal(data, snapshot); err != nil {
+			return nil, nil, err
+		}
+		for filename, fileMetaData := range snapshot.FileInfoMap {
+			fileMetaMap[filename] = fileMetaData
+		}
+	}
+
+	// 2. the updates accepted after the snapshot, in order
+	wal, records, err := OpenWriteAheadLog(filepath.Join(dir, metaWalFilename))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, record := range records {
+		fileMetaData := &FileMetaData{}
+		if err := proto.Unmarshal(record, fileMetaData); err != nil {
+			wal.Close()
+			return nil, nil, err
+		}
+		fileMetaMap[fileMetaData.Filename] = fileMetaData
+	}
+	return &MetaStoreLog{
+		Dir:           dir,
+		SnapshotEvery: snapshotEvery,
+		wal:           wal,
+		walRecords:    len(records),
+	}, fileMetaMap, nil
+}
+
+// Append makes one accepted update durable
+func (l *MetaStoreLog) Append(fileMetaData *FileMetaData) error {
+	record, err := proto.Marshal(fileMetaData)
+	if err != nil {
+		return err
+	}
+	if err := l.wal.Append(record); err != nil {
+		return err
+	}
+	l.walRecords++
+	return nil
+}
+
+// ShouldSnapshot reports whether the log has grown enough to be folded into a snapshot
+func (l *MetaStoreLog) ShouldSnapshot() bool {
+	return l.SnapshotEvery > 0 && l.walRecords >= l.SnapshotEvery
+}
+
+// Snapshot writes the whole map and empties the log. The caller must hold the MetaStore lock
+// so no update is appended in between. If we crash after the snapshot but before the log
+// is emptied, replaying the log on top of the snapshot ends in the same state.
+func (l *MetaStoreLog) Snapshot(fileMetaMap map[string]*FileMetaData) error {
+	data, err := proto.Marshal(&FileInfoMap{FileInfoMap: fileMetaMap})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(l.Dir, metaSnapshotFilename), data); err != nil {
+		return err
+	}
+	if err := l.wal.Reset(); err != nil {
+		return err
+	}
+	l.walRecords = 0
+	return nil
+}
+
+// SaveBlockStores remembers the configuration of the ring after AddBlockStore or RemoveBlockStore
+func (l *MetaStoreLog) SaveBlockStores(blockStores *BlockStoreAddrs) error {
+	data, err := proto.Marshal(blockStores)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(l.Dir, metaBlockStoresFilename), data)
+}
+
+// BlockStores returns the configuration saved by SaveBlockStores, nil if the
+// ring never changed and the block servers given on the command line still hold
+func (l *MetaStoreLog) BlockStores() (*BlockStoreAddrs, error) {
+	data, err := os.ReadFile(filepath.Join(l.Dir, metaBlockStoresFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	blockStores := &BlockStoreAddrs{}
+	if err := proto.Unmarshal(data, blockStores); err != nil {
+		return nil, err
+	}
+	return blockStores, nil
+}
+
+func (l *MetaStoreLog) Close() error {
+	return l.wal.Close()
+}
@@ -0,0 +1,60 @@
+package surfstore
+
+import (
+	context "context"
+	"testing"
+	"time"
+)
+
+func TestGarbageCollectionSweepsOnlyOldUnreferencedBlocks(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	live := []byte("live")
+	liveHash := GetBlockHashString(live)
+	if _, err := metaStore.UpdateFile(context.Background(), &FileMetaData{Filename: "a.bin", Version: 1, BlockHashList: []string{liveHash}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// on every server: the live block and three unreferenced ones, written before the grace period,
+	// within it, and before it but touched by MissingBlocks since, as when a client skips uploading it
+	const gracePeriod = time.Hour
+	old := time.Now().Add(-2 * gracePeriod)
+	garbage, recent, reused := []byte("garbage"), []byte("recent"), []byte("reused")
+	for _, blockStore := range blockStores {
+		storage := blockStore.BlockStore.Storage.(*MemoryBlockStorage)
+		for _, data := range [][]byte{live, garbage, recent, reused} {
+			if err := storage.Put(GetBlockHashString(data), &Block{BlockData: data, BlockSize: int32(len(data))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		storage.RWMutex.Lock()
+		storage.PutTime[liveHash] = old
+		storage.PutTime[GetBlockHashString(garbage)] = old
+		storage.PutTime[GetBlockHashString(reused)] = old
+		storage.RWMutex.Unlock()
+		if _, err := blockStore.BlockStore.MissingBlocks(context.Background(), &BlockHashes{Hashes: []string{GetBlockHashString(reused)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := metaStore.CollectGarbage(context.Background(), &GarbageCollection{GracePeriodSeconds: int64(gracePeriod / time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the garbage block on both servers, and the copy of the live block on the one that is not its replica
+	reclaimed := int64(2*len(garbage) + len(live))
+	if report.BlocksScanned != 8 || report.BlocksDeleted != 3 || report.BytesReclaimed != reclaimed {
+		t.Fatalf("scanned %d, deleted %d, reclaimed %d bytes; want 8, 3 and %d", report.BlocksScanned, report.BlocksDeleted, report.BytesReclaimed, reclaimed)
+	}
+	replica := metaStore.blockServers(metaStore.blockStores(), liveHash)[0]
+	for _, blockStore := range blockStores {
+		for data, want := range map[string]bool{string(garbage): false, string(recent): true, string(reused): true} {
+			if has, _ := blockStore.BlockStore.Storage.Has(GetBlockHashString([]byte(data))); has != want {
+				t.Fatalf("%s holds the %s block: %v, want %v", blockStore.Addr, data, has, want)
+			}
+		}
+		if has, _ := blockStore.BlockStore.Storage.Has(liveHash); has != (blockStore.Addr == replica) {
+			t.Fatalf("%s holds the live block: %v, its replica is %s", blockStore.Addr, has, replica)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package surfstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// PlacementStrategy decides which block servers hold a block. Every strategy is a pure function
+// of the servers, their weights and the block hash, so every MetaStore (and every raft replica)
+// places a block the same way, before and after a restart
+type PlacementStrategy interface {
+	// GetResponsibleServer returns the first server holding blockId
+	GetResponsibleServer(blockId string) string
+	// GetResponsibleServers returns the n distinct servers holding blockId, the first one is
+	// GetResponsibleServer. Fewer are returned if there are fewer servers.
+	GetResponsibleServers(blockId string, n int) []string
+}
+
+// rangePlacement is a strategy placing whole ranges of the hash space: every hash from one boundary
+// up to the next (or from the last one around to the first) is placed the same way. ok is false
+// when the strategy wraps one that does not
+type rangePlacement interface {
+	boundaries() (boundaries []string, ok bool)
+}
+
+var _ rangePlacement = new(ConsistentHashRing)
+var _ rangePlacement = new(boundedLoadRing)
+var _ rangePlacement = new(zoneAwarePlacement)
+
+var _ PlacementStrategy = new(ConsistentHashRing)
+var _ PlacementStrategy = new(rendezvousHashing)
+var _ PlacementStrategy = new(boundedLoadRing)
+var _ PlacementStrategy = new(zoneAwarePlacement)
+
+// NewPlacementStrategy builds the strategy called placement over servers. virtualNodes is only
+// used by the strategies built on the consistent hash ring
+func NewPlacementStrategy(placement string, servers []string, weights map[string]int32, virtualNodes int) (PlacementStrategy, error) {
+	switch placement {
+	case "", PLACEMENT_CONSISTENT:
+		return NewWeightedConsistentHashRing(servers, weights, virtualNodes), nil
+	case PLACEMENT_RENDEZVOUS:
+		return newRendezvousHashing(servers, weights), nil
+	case PLACEMENT_BOUNDED:
+		return newBoundedLoadRing(servers, weights, virtualNodes), nil
+	default:
+		return nil, fmt.Errorf("unknown placement %s", placement)
+	}
+}
+
+// serverWeight is the weight of a server, missing weights count as 1
+func serverWeight(weights map[string]int32, server string) int {
+	if w, ok := weights[server]; ok && w > 0 {
+		return int(w)
+	}
+	return 1
+}
+
+// Rendezvous (highest random weight) hashing: every server scores every block, and the block goes
+// to the servers with the highest scores. Adding or removing a server only moves the blocks it
+// wins or loses, and no virtual nodes are needed for an even spread. A lookup scores every server.
+type rendezvousHashing struct {
+	servers []string
+	weights []float64
+}
+
+func newRendezvousHashing(servers []string, weights map[string]int32) *rendezvousHashing {
+	r := &rendezvousHashing{
+		servers: append([]string{}, servers...),
+		weights: make([]float64, len(servers)),
+	}
+	for i, server := range servers {
+		r.weights[i] = float64(serverWeight(weights, server))
+	}
+	return r
+}
+
+func (r rendezvousHashing) GetResponsibleServer(blockId string) string {
+	servers := r.GetResponsibleServers(blockId, 1)
+	if len(servers) == 0 {
+		return ""
+	}
+	return servers[0]
+}
+
+func (r rendezvousHashing) GetResponsibleServers(blockId string, n int) []string {
+	scores := make([]float64, len(r.servers))
+	order := make([]int, len(r.servers))
+	for i, server := range r.servers {
+		scores[i] = rendezvousScore(server, blockId, r.weights[i])
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return r.servers[order[a]] < r.servers[order[b]]
+	})
+	servers := make([]string, 0, n)
+	for _, i := range order[:min(n, len(order))] {
+		servers = append(servers, r.servers[i])
+	}
+	return servers
+}
+
+// rendezvousScore is -weight / ln(u) for a u in (0, 1) drawn from the hash of server and block,
+// so a server wins a share of the blocks proportional to its weight
+func rendezvousScore(server string, blockId string, weight float64) float64 {
+	sum := sha256.Sum256([]byte(server + "/" + blockId))
+	u := (float64(binary.BigEndian.Uint64(sum[:8])>>11) + 0.5) / (1 << 53)
+	return -weight / math.Log(u)
+}
+
+// Consistent hashing with bounded loads: the hash space is cut into partitions by the first
+// boundedLoadDigits hex digits of a hash. Going through the partitions in order, each goes to the
+// first server after it on the ring that still has room, a server having room for at most
+// 1+BOUNDED_LOAD_EPSILON times its (weighted) fair share of the partitions. Blocks are spread over
+// the partitions by their hash, so no server takes much more than its share, even with few virtual
+// nodes. Only the first replica is bounded, the others are the next distinct servers on the ring.
+type boundedLoadRing struct {
+	ring *ConsistentHashRing
+	// partition -> index of the point of the ring it was placed at
+	points []int
+}
+
+// 4096 partitions
+const boundedLoadDigits = 3
+
+func newBoundedLoadRing(servers []string, weights map[string]int32, virtualNodes int) *boundedLoadRing {
+	b := &boundedLoadRing{ring: NewWeightedConsistentHashRing(servers, weights, virtualNodes)}
+	if len(b.ring.hashes) == 0 {
+		return b
+	}
+	partitions := 1 << (4 * boundedLoadDigits)
+	totalWeight := 0
+	for _, server := range servers {
+		totalWeight += serverWeight(weights, server)
+	}
+	capacity := map[string]int{}
+	for _, server := range servers {
+		share := float64(partitions) * float64(serverWeight(weights, server)) / float64(totalWeight)
+		capacity[server] = int(math.Ceil((1 + BOUNDED_LOAD_EPSILON) * share))
+	}
+	// the capacities add up to more than the partitions, so every partition finds a server
+	load := map[string]int{}
+	b.points = make([]int, partitions)
+	for p := range b.points {
+		start := b.ring.successor(fmt.Sprintf("%0*x", boundedLoadDigits, p))
+		for i := 0; i < len(b.ring.hashes); i++ {
+			point := (start + i) % len(b.ring.hashes)
+			if server := b.ring.servers[point]; load[server] < capacity[server] {
+				load[server]++
+				b.points[p] = point
+				break
+			}
+		}
+	}
+	return b
+}
+
+func (b boundedLoadRing) GetResponsibleServer(blockId string) string {
+	servers := b.GetResponsibleServers(blockId, 1)
+	if len(servers) == 0 {
+		return ""
+	}
+	return servers[0]
+}
+
+func (b boundedLoadRing) GetResponsibleServers(blockId string, n int) []string {
+	if len(b.points) == 0 {
+		return b.ring.GetResponsibleServers(blockId, n)
+	}
+	p, err := strconv.ParseUint(blockId[:min(boundedLoadDigits, len(blockId))], 16, 64)
+	if err != nil || len(blockId) < boundedLoadDigits {
+		// not a block hash, no partition
+		return b.ring.GetResponsibleServers(blockId, n)
+	}
+	servers := make([]string, 0, n)
+	start := b.points[p]
+	for i := 0; i < len(b.ring.hashes) && len(servers) < n; i++ {
+		server := b.ring.servers[(start+i)%len(b.ring.hashes)]
+		if !containsString(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// boundaries are the starts of the partitions, a partition goes to one point of the ring
+func (b boundedLoadRing) boundaries() ([]string, bool) {
+	if len(b.points) == 0 {
+		return b.ring.boundaries()
+	}
+	starts := make([]string, len(b.points))
+	for p := range starts {
+		starts[p] = fmt.Sprintf("%0*x", boundedLoadDigits, p)
+	}
+	return starts, true
+}
+
+// zoneAwarePlacement spreads the replicas of a block over distinct zones whenever there are enough
+// zones: going down the servers another strategy prefers for the block, it takes the first server
+// of each zone, and fills any replicas left over with the next preferred servers. The first replica
+// stays where the other strategy puts it. A server without a zone is a zone of its own.
+type zoneAwarePlacement struct {
+	placement PlacementStrategy
+	// server -> zone
+	zones map[string]string
+	// how many zones there are, counting every server without one
+	zoneCount int
+}
+
+// NewZoneAwarePlacement wraps placement so the replicas of a block land in distinct zones.
+// zones gives the zone of each of the servers, servers missing from it have no zone
+func NewZoneAwarePlacement(placement PlacementStrategy, servers []string, zones map[string]string) PlacementStrategy {
+	z := &zoneAwarePlacement{placement: placement, zones: map[string]string{}}
+	seen := map[string]bool{}
+	for _, server := range servers {
+		zone := zones[server]
+		if zone == "" {
+			z.zoneCount++
+			continue
+		}
+		z.zones[server] = zone
+		if !seen[zone] {
+			seen[zone] = true
+			z.zoneCount++
+		}
+	}
+	return z
+}
+
+func (z zoneAwarePlacement) GetResponsibleServer(blockId string) string {
+	return z.placement.GetResponsibleServer(blockId)
+}
+
+func (z zoneAwarePlacement) GetResponsibleServers(blockId string, n int) []string {
+	if n < 1 {
+		return []string{}
+	}
+	// ask for more of the preferred servers until n zones are covered, or there are no more
+	for k := n; ; k *= 2 {
+		candidates := z.placement.GetResponsibleServers(blockId, k)
+		servers, zonesCovered := z.pick(candidates, n)
+		if zonesCovered == min(n, z.zoneCount) || len(candidates) < k {
+			return servers
+		}
+	}
+}
+
+// boundaries are those of the wrapped strategy, the zones of the candidates it prefers are the same
+// for every hash of one of its ranges
+func (z zoneAwarePlacement) boundaries() ([]string, bool) {
+	if placement, ok := z.placement.(rangePlacement); ok {
+		return placement.boundaries()
+	}
+	return nil, false
+}
+
+// pick takes the first candidate of each zone, then the other candidates in order, up to n servers.
+// It also returns how many zones the servers are in
+func (z zoneAwarePlacement) pick(candidates []string, n int) ([]string, int) {
+	servers := make([]string, 0, n)
+	used := map[string]bool{}
+	taken := make([]bool, len(candidates))
+	for i, server := range candidates {
+		if len(servers) == n {
+			break
+		}
+		if zone, ok := z.zones[server]; ok {
+			if used[zone] {
+				continue
+			}
+			used[zone] = true
+		}
+		taken[i] = true
+		servers = append(servers, server)
+	}
+	zonesCovered := len(servers)
+	for i, server := range candidates {
+		if len(servers) == n {
+			break
+		}
+		if !taken[i] {
+			servers = append(servers, server)
+		}
+	}
+	return servers, zonesCovered
+}
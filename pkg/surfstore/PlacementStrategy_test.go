@@ -0,0 +1,186 @@
+package surfstore
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testHashes returns n block hashes
+func testHashes(n int) []string {
+	hashes := make([]string, n)
+	for i := range hashes {
+		hashes[i] = GetBlockHashString([]byte(strconv.Itoa(i)))
+	}
+	return hashes
+}
+
+func testServers(n int) []string {
+	servers := make([]string, n)
+	for i := range servers {
+		servers[i] = "localhost:" + strconv.Itoa(8081+i)
+	}
+	return servers
+}
+
+func TestPlacementPicksDistinctServers(t *testing.T) {
+	servers := testServers(5)
+	for _, placement := range []string{PLACEMENT_CONSISTENT, PLACEMENT_RENDEZVOUS, PLACEMENT_BOUNDED} {
+		strategy, err := NewPlacementStrategy(placement, servers, map[string]int32{servers[0]: 3}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, hash := range testHashes(1000) {
+			for n := 1; n <= len(servers)+1; n++ {
+				replicas := strategy.GetResponsibleServers(hash, n)
+				if len(replicas) != min(n, len(servers)) {
+					t.Fatalf("%s: %d replicas of %s, want %d", placement, len(replicas), hash, min(n, len(servers)))
+				}
+				seen := map[string]bool{}
+				for _, replica := range replicas {
+					if seen[replica] {
+						t.Fatalf("%s: %s is twice among the replicas %v of %s", placement, replica, replicas, hash)
+					}
+					seen[replica] = true
+				}
+				if replicas[0] != strategy.GetResponsibleServer(hash) {
+					t.Fatalf("%s: the first of %d replicas of %s is %s, not its server %s", placement, n, hash, replicas[0], strategy.GetResponsibleServer(hash))
+				}
+			}
+		}
+	}
+}
+
+func TestBoundedLoadKeepsEveryServerWithinItsShare(t *testing.T) {
+	servers := testServers(7)
+	weights := map[string]int32{servers[0]: 2, servers[1]: 3}
+	// one virtual node each, a plain ring is far from even
+	strategy, err := NewPlacementStrategy(PLACEMENT_BOUNDED, servers, weights, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partitions := 1 << (4 * boundedLoadDigits)
+	load := map[string]int{}
+	for p := 0; p < partitions; p++ {
+		load[strategy.GetResponsibleServer(fmt.Sprintf("%0*x", boundedLoadDigits, p)+strings.Repeat("0", 64-boundedLoadDigits))]++
+	}
+	totalWeight := 0
+	for _, server := range servers {
+		totalWeight += serverWeight(weights, server)
+	}
+	for _, server := range servers {
+		share := float64(partitions) * float64(serverWeight(weights, server)) / float64(totalWeight)
+		if bound := int(math.Ceil((1 + BOUNDED_LOAD_EPSILON) * share)); load[server] > bound {
+			t.Fatalf("%s holds %d partitions, more than its bound of %d", server, load[server], bound)
+		}
+	}
+}
+
+func TestPlacementMovesFewBlocksWhenAServerJoins(t *testing.T) {
+	servers := testServers(5)
+	joined := append(append([]string{}, servers...), "localhost:9000")
+	hashes := testHashes(5000)
+	// a sixth server takes a sixth of the blocks; bounded loads may move some more to stay within bounds
+	for placement, most := range map[string]float64{PLACEMENT_CONSISTENT: 0.3, PLACEMENT_RENDEZVOUS: 0.25, PLACEMENT_BOUNDED: 0.4} {
+		before, err := NewPlacementStrategy(placement, servers, nil, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := NewPlacementStrategy(placement, joined, nil, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moved := 0
+		for _, hash := range hashes {
+			from, to := before.GetResponsibleServer(hash), after.GetResponsibleServer(hash)
+			if from == to {
+				continue
+			}
+			moved++
+			if placement != PLACEMENT_BOUNDED && to != "localhost:9000" {
+				t.Fatalf("%s: block %s moved from %s to %s, not to the server that joined", placement, hash, from, to)
+			}
+		}
+		if fraction := float64(moved) / float64(len(hashes)); fraction == 0 || fraction > most {
+			t.Fatalf("%s: %.2f of the blocks moved when a sixth server joined", placement, fraction)
+		}
+	}
+}
+
+func TestZoneAwarePlacementSpreadsReplicasOverZones(t *testing.T) {
+	servers := testServers(7)
+	// three zones of two, and a server without a zone, which is a zone of its own
+	zones := map[string]string{}
+	for i, server := range servers[:6] {
+		zones[server] = []string{"east", "west", "north"}[i%3]
+	}
+	zoneOf := func(server string) string {
+		if zone, ok := zones[server]; ok {
+			return zone
+		}
+		return server
+	}
+	for _, placement := range []string{PLACEMENT_CONSISTENT, PLACEMENT_RENDEZVOUS, PLACEMENT_BOUNDED} {
+		inner, err := NewPlacementStrategy(placement, servers, nil, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strategy := NewZoneAwarePlacement(inner, servers, zones)
+		for _, hash := range testHashes(1000) {
+			for n := 1; n <= len(servers); n++ {
+				replicas := strategy.GetResponsibleServers(hash, n)
+				if len(replicas) != n {
+					t.Fatalf("%s: %d replicas of %s, want %d", placement, len(replicas), hash, n)
+				}
+				if replicas[0] != inner.GetResponsibleServer(hash) {
+					t.Fatalf("%s: the first replica of %s moved from %s to %s", placement, hash, inner.GetResponsibleServer(hash), replicas[0])
+				}
+				seen := map[string]bool{}
+				zonesCovered := map[string]bool{}
+				for _, replica := range replicas {
+					if seen[replica] {
+						t.Fatalf("%s: %s is twice among the replicas %v of %s", placement, replica, replicas, hash)
+					}
+					seen[replica] = true
+					zonesCovered[zoneOf(replica)] = true
+				}
+				// one replica per zone while there are enough zones, every zone once there are not
+				if len(zonesCovered) != min(n, 4) {
+					t.Fatalf("%s: the %d replicas %v of %s are in %d zones", placement, n, replicas, hash, len(zonesCovered))
+				}
+			}
+		}
+	}
+}
+
+func TestZoneAwarePlacementMovesFewBlocksWhenAServerJoins(t *testing.T) {
+	servers := testServers(6)
+	zones := map[string]string{}
+	for i, server := range servers {
+		zones[server] = []string{"east", "west", "north"}[i%3]
+	}
+	joined := append(append([]string{}, servers...), "localhost:9000")
+	zones["localhost:9000"] = "east"
+	before := NewZoneAwarePlacement(NewWeightedConsistentHashRing(servers, nil, 20), servers, zones)
+	after := NewZoneAwarePlacement(NewWeightedConsistentHashRing(joined, nil, 20), joined, zones)
+
+	hashes := testHashes(5000)
+	moved := 0
+	for _, hash := range hashes {
+		from, to := before.GetResponsibleServers(hash, 3), after.GetResponsibleServers(hash, 3)
+		for _, replica := range to {
+			if !containsString(from, replica) && replica != "localhost:9000" {
+				t.Fatalf("block %s moved from %v to %v, the new replica is not the server that joined", hash, from, to)
+			}
+		}
+		if !CompareBlockHashList(from, to) {
+			moved++
+		}
+	}
+	// the new server takes a share of the replicas of its zone, at most half of it here
+	if fraction := float64(moved) / float64(len(hashes)); fraction == 0 || fraction > 0.5 {
+		t.Fatalf("%.2f of the blocks changed replicas when a server joined", fraction)
+	}
+}
@@ -0,0 +1,720 @@
+package surfstore
+
+import (
+	context "context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+type raftRole int
+
+const (
+	raftFollower raftRole = iota
+	raftCandidate
+	raftLeader
+)
+
+// raftResult is how the applier hands the outcome of an UpdateFile back to the waiting client
+type raftResult struct {
+	term    int64
+	version chan *Version
+}
+
+// RaftSurfstore is one server of a replicated MetaStore. Every UpdateFile goes through the
+// raft log and is applied to MetaStore on every server once a majority has it.
+// Only the leader serves clients, the other servers answer ERR_NOT_LEADER.
+type RaftSurfstore struct {
+	Id         int64
+	NumServers int64
+	// MetaStore is the state machine the committed log entries are applied to
+	MetaStore *MetaStore
+	Transport RaftTransport
+	// Storage keeps term, vote and log across restarts, nil if they only live in memory
+	Storage *RaftStorage
+
+	HeartbeatInterval time.Duration
+	ElectionTimeout   time.Duration
+
+	mu      sync.Mutex
+	changed *sync.Cond // broadcast whenever commitIndex, lastApplied, role or acks change
+
+	// persistent state
+	term     int64
+	votedFor int64
+	log      []*UpdateOperation // log[i-1] holds log index i
+
+	// volatile state
+	commitIndex int64
+	lastApplied int64
+	role        raftRole
+	leaderId    int64
+	votes       int64
+	lastHeard   time.Time
+	timeout     time.Duration // randomized election timeout
+
+	// leader state
+	nextIndex  []int64
+	matchIndex []int64
+	inflight   []bool
+	ackedAt    []time.Time // last time a server answered an AppendEntries sent at that time in this term
+	results    map[int64]*raftResult
+
+	crashed   bool
+	stopped   bool
+	replicate chan struct{}
+
+	UnimplementedMetaStoreServer
+	UnimplementedRaftSurfstoreServer
+}
+
+func (r *RaftSurfstore) GetFileInfoMap(ctx context.Context, empty *emptypb.Empty) (*FileInfoMap, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.GetFileInfoMap(ctx, empty)
+}
+
+func (r *RaftSurfstore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
+	// turned away before it is in the log, every server would fail to apply it
+	if _, err := stripeShards(fileMetaData); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return r.propose(ctx, &UpdateOperation{FileMetaData: fileMetaData})
+}
+
+// Only the leader migrates blocks, the new ring is then replicated like any update
+func (r *RaftSurfstore) AddBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.changeBlockStores(blockStoreAddr, true, r.commitBlockStores(ctx))
+}
+
+func (r *RaftSurfstore) RemoveBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.changeBlockStores(blockStoreAddr, false, r.commitBlockStores(ctx))
+}
+
+func (r *RaftSurfstore) SetBlockStoreAddr(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*Success, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.changeBlockStoreAddr(blockStoreAddr, r.commitBlockStores(ctx))
+}
+
+func (r *RaftSurfstore) RegisterBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.registerBlockStore(blockStoreAddr, r.commitBlockStores(ctx))
+}
+
+func (r *RaftSurfstore) commitBlockStores(ctx context.Context) func(blockStores *BlockStoreAddrs) error {
+	return func(blockStores *BlockStoreAddrs) error {
+		_, err := r.propose(ctx, &UpdateOperation{BlockStoreAddrs: blockStores})
+		return err
+	}
+}
+
+// propose appends entry to the log in the current term and waits for it to be committed and applied
+func (r *RaftSurfstore) propose(ctx context.Context, entry *UpdateOperation) (*Version, error) {
+	r.mu.Lock()
+	if r.crashed {
+		r.mu.Unlock()
+		return nil, ERR_SERVER_CRASHED
+	}
+	if r.role != raftLeader {
+		r.mu.Unlock()
+		return nil, ERR_NOT_LEADER
+	}
+	entry.Term = r.term
+	if err := r.appendLocked(entry); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	result := &raftResult{term: r.term, version: make(chan *Version, 1)}
+	r.results[int64(len(r.log))] = result
+	r.advanceCommitLocked()
+	r.kick()
+	r.mu.Unlock()
+
+	// wait for the entry to be committed and applied
+	select {
+	case version := <-result.version:
+		if version == nil {
+			return nil, ERR_LEADERSHIP_LOST
+		}
+		return version, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *RaftSurfstore) GetBlockStoreMap(ctx context.Context, blockHashesIn *BlockHashes) (*BlockStoreMap, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.GetBlockStoreMap(ctx, blockHashesIn)
+}
+
+func (r *RaftSurfstore) GetBlockStoreAddrs(ctx context.Context, empty *emptypb.Empty) (*BlockStoreAddrs, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.GetBlockStoreAddrs(ctx, empty)
+}
+
+// Only the leader collects garbage, its MetaStore has every committed file
+func (r *RaftSurfstore) CollectGarbage(ctx context.Context, gc *GarbageCollection) (*GarbageCollectionReport, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.CollectGarbage(ctx, gc)
+}
+
+// The leader reports what its own probes found out, followers probe too so a new leader knows right away
+func (r *RaftSurfstore) GetClusterStatus(ctx context.Context, empty *emptypb.Empty) (*ClusterStatus, error) {
+	if err := r.waitReadable(ctx); err != nil {
+		return nil, err
+	}
+	return r.MetaStore.GetClusterStatus(ctx, empty)
+}
+
+// waitReadable returns once this server has confirmed with a majority that it is still the leader
+// and has applied everything committed, so a read cannot miss an acknowledged update
+func (r *RaftSurfstore) waitReadable(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		r.mu.Lock()
+		r.changed.Broadcast()
+		r.mu.Unlock()
+	})
+	defer stop()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	term := r.term
+	start := time.Now()
+	r.kick()
+	for {
+		if r.crashed {
+			return ERR_SERVER_CRASHED
+		}
+		if r.role != raftLeader || r.term != term {
+			return ERR_NOT_LEADER
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		acks := int64(1)
+		for id := int64(0); id < r.NumServers; id++ {
+			if id != r.Id && r.ackedAt[id].After(start) {
+				acks++
+			}
+		}
+		// the leader's first entry of its term tells it how far the log is committed
+		if acks*2 > r.NumServers && r.termAt(r.commitIndex) == term && r.lastApplied >= r.commitIndex {
+			return nil
+		}
+		r.changed.Wait()
+	}
+}
+
+func (r *RaftSurfstore) AppendEntries(ctx context.Context, input *AppendEntryInput) (*AppendEntryOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.crashed {
+		return nil, ERR_SERVER_CRASHED
+	}
+	output := &AppendEntryOutput{ServerId: r.Id, Term: r.term}
+	if input.Term < r.term {
+		return output, nil
+	}
+	if input.Term > r.term || r.role != raftFollower {
+		if err := r.becomeFollowerLocked(input.Term); err != nil {
+			return nil, err
+		}
+	}
+	output.Term = r.term
+	r.leaderId = input.LeaderId
+	r.lastHeard = time.Now()
+
+	// 1. our log has to contain the entry right before the new ones
+	if input.PrevLogIndex > int64(len(r.log)) || r.termAt(input.PrevLogIndex) != input.PrevLogTerm {
+		output.MatchedIndex = min(int64(len(r.log)), input.PrevLogIndex-1)
+		return output, nil
+	}
+
+	// 2. skip entries we already have, drop ours from the first conflict on, append the rest
+	for i, entry := range input.Entries {
+		index := input.PrevLogIndex + 1 + int64(i)
+		if index <= int64(len(r.log)) {
+			if r.log[index-1].Term == entry.Term {
+				continue
+			}
+			r.log = r.log[:index-1]
+			if r.Storage != nil {
+				if err := r.Storage.ReplaceLog(r.log); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := r.appendLocked(input.Entries[i:]...); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	// 3. commit what the leader has committed
+	matched := input.PrevLogIndex + int64(len(input.Entries))
+	if input.LeaderCommit > r.commitIndex {
+		r.commitIndex = min(input.LeaderCommit, matched)
+		r.changed.Broadcast()
+	}
+	output.Success = true
+	output.MatchedIndex = matched
+	return output, nil
+}
+
+func (r *RaftSurfstore) RequestVote(ctx context.Context, input *RequestVoteInput) (*RequestVoteOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.crashed {
+		return nil, ERR_SERVER_CRASHED
+	}
+	if input.Term > r.term {
+		if err := r.becomeFollowerLocked(input.Term); err != nil {
+			return nil, err
+		}
+	}
+	output := &RequestVoteOutput{ServerId: r.Id, Term: r.term}
+	if input.Term < r.term {
+		return output, nil
+	}
+	lastIndex := int64(len(r.log))
+	lastTerm := r.termAt(lastIndex)
+	upToDate := input.LastLogTerm > lastTerm || (input.LastLogTerm == lastTerm && input.LastLogIndex >= lastIndex)
+	if (r.votedFor == -1 || r.votedFor == input.CandidateId) && upToDate {
+		if r.Storage != nil {
+			if err := r.Storage.SaveState(r.term, input.CandidateId); err != nil {
+				return nil, err
+			}
+		}
+		r.votedFor = input.CandidateId
+		r.lastHeard = time.Now()
+		output.VoteGranted = true
+	}
+	return output, nil
+}
+
+// Crash makes the server stop acting and answering, like a crashed process that keeps its disk
+func (r *RaftSurfstore) Crash() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.crashed = true
+	r.role = raftFollower
+	r.failResultsLocked()
+	r.changed.Broadcast()
+}
+
+// Restore brings a crashed server back as a follower
+func (r *RaftSurfstore) Restore() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.crashed = false
+	r.resetElectionTimerLocked()
+	r.changed.Broadcast()
+}
+
+// IsLeader reports whether the server currently believes it is the leader
+func (r *RaftSurfstore) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.crashed && r.role == raftLeader
+}
+
+// Start runs the election timer, heartbeats and the applier in the background
+func (r *RaftSurfstore) Start() {
+	go r.ticker()
+	go r.applier()
+}
+
+// Stop ends the background goroutines started by Start
+func (r *RaftSurfstore) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	r.failResultsLocked()
+	r.changed.Broadcast()
+}
+
+func (r *RaftSurfstore) ticker() {
+	heartbeat := time.NewTicker(r.HeartbeatInterval)
+	defer heartbeat.Stop()
+	check := time.NewTicker(r.HeartbeatInterval / 5)
+	defer check.Stop()
+	for {
+		kicked := false
+		select {
+		case <-heartbeat.C:
+			kicked = true
+		case <-r.replicate:
+			kicked = true
+		case <-check.C:
+		}
+		r.mu.Lock()
+		if r.stopped {
+			r.mu.Unlock()
+			return
+		}
+		if !r.crashed {
+			if r.role == raftLeader {
+				if kicked {
+					r.broadcastLocked()
+				}
+			} else if time.Since(r.lastHeard) >= r.timeout {
+				r.startElectionLocked()
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *RaftSurfstore) startElectionLocked() {
+	if r.Storage != nil {
+		if err := r.Storage.SaveState(r.term+1, r.Id); err != nil {
+			log.Printf("Raft server %d cannot save its state: %v", r.Id, err)
+			r.resetElectionTimerLocked()
+			return
+		}
+	}
+	r.term++
+	r.votedFor = r.Id
+	r.role = raftCandidate
+	r.votes = 1
+	r.resetElectionTimerLocked()
+	if r.votes*2 > r.NumServers { // a cluster of one
+		r.becomeLeaderLocked()
+		return
+	}
+	input := &RequestVoteInput{
+		Term:         r.term,
+		CandidateId:  r.Id,
+		LastLogIndex: int64(len(r.log)),
+		LastLogTerm:  r.termAt(int64(len(r.log))),
+	}
+	for id := int64(0); id < r.NumServers; id++ {
+		if id != r.Id {
+			go r.requestVote(id, input)
+		}
+	}
+}
+
+func (r *RaftSurfstore) requestVote(id int64, input *RequestVoteInput) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.ElectionTimeout)
+	defer cancel()
+	output, err := r.Transport.RequestVote(ctx, id, input)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.crashed || r.stopped {
+		return
+	}
+	if output.Term > r.term {
+		if err := r.becomeFollowerLocked(output.Term); err != nil {
+			log.Printf("Raft server %d cannot save its state: %v", r.Id, err)
+		}
+		return
+	}
+	if r.role != raftCandidate || r.term != input.Term || !output.VoteGranted {
+		return
+	}
+	r.votes++
+	if r.votes*2 > r.NumServers {
+		r.becomeLeaderLocked()
+	}
+}
+
+func (r *RaftSurfstore) becomeLeaderLocked() {
+	r.role = raftLeader
+	r.leaderId = r.Id
+	for id := int64(0); id < r.NumServers; id++ {
+		r.nextIndex[id] = int64(len(r.log)) + 1
+		r.matchIndex[id] = 0
+		r.ackedAt[id] = time.Time{}
+	}
+	// an entry of our own term lets us find out what is committed. Until an entry sets the ring, the
+	// first leader puts its own in, so every server places blocks by the node IDs this one resolved
+	entry := &UpdateOperation{Term: r.term}
+	if !r.hasBlockStoresLocked() {
+		entry.BlockStoreAddrs = r.MetaStore.blockStores().config
+	}
+	if err := r.appendLocked(entry); err != nil {
+		log.Printf("Raft server %d cannot append to its log: %v", r.Id, err)
+		r.role = raftFollower
+		return
+	}
+	log.Printf("Raft server %d is the leader of term %d", r.Id, r.term)
+	r.advanceCommitLocked()
+	r.broadcastLocked()
+	r.changed.Broadcast()
+}
+
+func (r *RaftSurfstore) becomeFollowerLocked(term int64) error {
+	if term > r.term {
+		if r.Storage != nil {
+			if err := r.Storage.SaveState(term, -1); err != nil {
+				return err
+			}
+		}
+		r.term = term
+		r.votedFor = -1
+	}
+	if r.role == raftLeader {
+		r.failResultsLocked()
+	}
+	r.role = raftFollower
+	r.changed.Broadcast()
+	return nil
+}
+
+func (r *RaftSurfstore) broadcastLocked() {
+	for id := int64(0); id < r.NumServers; id++ {
+		if id != r.Id && !r.inflight[id] {
+			r.inflight[id] = true
+			go r.replicateTo(id, r.term)
+		}
+	}
+}
+
+// replicateTo sends one AppendEntries to server id, with every entry it is missing
+func (r *RaftSurfstore) replicateTo(id int64, term int64) {
+	r.mu.Lock()
+	if r.crashed || r.stopped || r.role != raftLeader || r.term != term {
+		r.inflight[id] = false
+		r.mu.Unlock()
+		return
+	}
+	prevLogIndex := r.nextIndex[id] - 1
+	// a follower far behind catches up over several calls, each well under the message limit
+	entries := make([]*UpdateOperation, 0, min(len(r.log)-int(prevLogIndex), RAFT_MAX_APPEND_ENTRIES))
+	size := 0
+	for _, entry := range r.log[prevLogIndex:] {
+		if len(entries) == RAFT_MAX_APPEND_ENTRIES || (len(entries) > 0 && size+proto.Size(entry) > RAFT_MAX_APPEND_BYTES) {
+			break
+		}
+		entries = append(entries, entry)
+		size += proto.Size(entry)
+	}
+	input := &AppendEntryInput{
+		Term:         r.term,
+		LeaderId:     r.Id,
+		PrevLogIndex: prevLogIndex,
+		PrevLogTerm:  r.termAt(prevLogIndex),
+		Entries:      entries,
+		LeaderCommit: r.commitIndex,
+	}
+	r.mu.Unlock()
+
+	sentAt := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), r.ElectionTimeout)
+	output, err := r.Transport.AppendEntries(ctx, id, input)
+	cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inflight[id] = false
+	if err != nil || r.crashed || r.stopped {
+		return
+	}
+	if output.Term > r.term {
+		if err := r.becomeFollowerLocked(output.Term); err != nil {
+			log.Printf("Raft server %d cannot save its state: %v", r.Id, err)
+		}
+		return
+	}
+	if r.role != raftLeader || r.term != term {
+		return
+	}
+	r.ackedAt[id] = sentAt
+	r.changed.Broadcast()
+	if output.Success {
+		r.matchIndex[id] = max(r.matchIndex[id], output.MatchedIndex)
+		r.nextIndex[id] = r.matchIndex[id] + 1
+		r.advanceCommitLocked()
+	} else {
+		// walk back to the last entry the follower may have
+		r.nextIndex[id] = max(1, min(r.nextIndex[id]-1, output.MatchedIndex+1))
+	}
+	if r.nextIndex[id] <= int64(len(r.log)) {
+		r.inflight[id] = true
+		go r.replicateTo(id, term)
+	}
+}
+
+// advanceCommitLocked commits the newest entry of this term that a majority has
+func (r *RaftSurfstore) advanceCommitLocked() {
+	for index := int64(len(r.log)); index > r.commitIndex; index-- {
+		if r.log[index-1].Term != r.term {
+			return
+		}
+		count := int64(1)
+		for id := int64(0); id < r.NumServers; id++ {
+			if id != r.Id && r.matchIndex[id] >= index {
+				count++
+			}
+		}
+		if count*2 > r.NumServers {
+			r.commitIndex = index
+			r.changed.Broadcast()
+			return
+		}
+	}
+}
+
+// applier applies committed entries to the MetaStore in log order
+func (r *RaftSurfstore) applier() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		for !r.stopped && (r.crashed || r.lastApplied >= r.commitIndex) {
+			r.changed.Wait()
+		}
+		if r.stopped {
+			return
+		}
+		index := r.lastApplied + 1
+		entry := r.log[index-1]
+		r.mu.Unlock()
+
+		var version *Version
+		if entry.FileMetaData != nil {
+			var err error
+			if version, err = r.MetaStore.UpdateFile(context.Background(), entry.FileMetaData); err != nil {
+				log.Fatalf("Raft server %d cannot apply log entry %d: %v", r.Id, index, err)
+			}
+		}
+		if entry.BlockStoreAddrs != nil {
+			if err := r.MetaStore.SetBlockStores(entry.BlockStoreAddrs); err != nil {
+				log.Fatalf("Raft server %d cannot apply log entry %d: %v", r.Id, index, err)
+			}
+			version = &Version{}
+		}
+
+		r.mu.Lock()
+		r.lastApplied = index
+		if result, ok := r.results[index]; ok {
+			delete(r.results, index)
+			if result.term == entry.Term {
+				result.version <- version
+			} else {
+				result.version <- nil
+			}
+		}
+		r.changed.Broadcast()
+	}
+}
+
+// appendLocked adds entries to the end of the log, saving them first
+func (r *RaftSurfstore) appendLocked(entries ...*UpdateOperation) error {
+	if r.Storage != nil {
+		if err := r.Storage.AppendEntries(entries); err != nil {
+			return err
+		}
+	}
+	r.log = append(r.log, entries...)
+	return nil
+}
+
+// HasBlockStores reports whether an entry of the log sets the ring, the ring the MetaStore was
+// created with is then replaced once the log is applied
+func (r *RaftSurfstore) HasBlockStores() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hasBlockStoresLocked()
+}
+
+func (r *RaftSurfstore) hasBlockStoresLocked() bool {
+	for _, entry := range r.log {
+		if entry.BlockStoreAddrs != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// failResultsLocked tells every waiting client that its update may not commit here
+func (r *RaftSurfstore) failResultsLocked() {
+	for index, result := range r.results {
+		result.version <- nil
+		delete(r.results, index)
+	}
+}
+
+func (r *RaftSurfstore) termAt(index int64) int64 {
+	if index <= 0 || index > int64(len(r.log)) {
+		return 0
+	}
+	return r.log[index-1].Term
+}
+
+func (r *RaftSurfstore) resetElectionTimerLocked() {
+	r.lastHeard = time.Now()
+	r.timeout = r.ElectionTimeout + time.Duration(rand.Int63n(int64(r.ElectionTimeout)))
+}
+
+func (r *RaftSurfstore) kick() {
+	select {
+	case r.replicate <- struct{}{}:
+	default:
+	}
+}
+
+// This line guarantees all method for RaftSurfstore are implemented
+var _ RaftInterface = new(RaftSurfstore)
+
+// NewRaftSurfstore creates server id of a cluster of numServers servers, applying to metaStore.
+// With a dataDir the raft state is saved there and recovered from there, otherwise it only lives in memory.
+// Call Start to run it.
+func NewRaftSurfstore(id int64, numServers int64, metaStore *MetaStore, transport RaftTransport, dataDir string) (*RaftSurfstore, error) {
+	r := &RaftSurfstore{
+		Id:                id,
+		NumServers:        numServers,
+		MetaStore:         metaStore,
+		Transport:         transport,
+		HeartbeatInterval: RAFT_HEARTBEAT_INTERVAL,
+		ElectionTimeout:   RAFT_ELECTION_TIMEOUT,
+		votedFor:          -1,
+		log:               make([]*UpdateOperation, 0),
+		leaderId:          -1,
+		nextIndex:         make([]int64, numServers),
+		matchIndex:        make([]int64, numServers),
+		inflight:          make([]bool, numServers),
+		ackedAt:           make([]time.Time, numServers),
+		results:           make(map[int64]*raftResult),
+		replicate:         make(chan struct{}, 1),
+	}
+	r.changed = sync.NewCond(&r.mu)
+	if dataDir != "" {
+		storage, state, entries, err := OpenRaftStorage(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		r.Storage = storage
+		r.term = state.Term
+		r.votedFor = state.VotedFor
+		r.log = entries
+	}
+	r.resetElectionTimerLocked()
+	return r, nil
+}
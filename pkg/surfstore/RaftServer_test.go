@@ -0,0 +1,334 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// how long a test waits for the cluster to elect a leader or catch up
+const raftTestTimeout time.Duration = 5 * time.Second
+
+func TestRaftElectsOneLeader(t *testing.T) {
+	servers := newTestRaftCluster(t, 3)
+	leader := waitForLeader(t, servers)
+
+	// the leader keeps its term while it reaches everyone
+	time.Sleep(3 * RAFT_ELECTION_TIMEOUT)
+	if !servers[leader].IsLeader() {
+		t.Fatalf("server %d lost the lead without a failure", leader)
+	}
+	for id, server := range servers {
+		if int64(id) != leader && server.IsLeader() {
+			t.Fatalf("servers %d and %d are both leaders", leader, id)
+		}
+	}
+	if _, err := servers[leader].GetFileInfoMap(testContext(t), &emptypb.Empty{}); err != nil {
+		t.Fatalf("the leader cannot serve reads: %v", err)
+	}
+}
+
+func TestRaftNewLeaderKeepsCommittedUpdates(t *testing.T) {
+	servers := newTestRaftCluster(t, 3)
+	leader := waitForLeader(t, servers)
+	for _, filename := range []string{"a.txt", "b.txt", "c.txt"} {
+		updateTestFile(t, servers[leader], filename, 1)
+	}
+
+	servers[leader].Crash()
+	newLeader := waitForLeader(t, servers)
+	if newLeader == leader {
+		t.Fatal("a crashed server is still the leader")
+	}
+	fileInfoMap, err := servers[newLeader].GetFileInfoMap(testContext(t), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{"a.txt", "b.txt", "c.txt"} {
+		if fileInfoMap.FileInfoMap[filename].GetVersion() != 1 {
+			t.Fatalf("the new leader lost the committed update of %s", filename)
+		}
+	}
+	updateTestFile(t, servers[newLeader], "a.txt", 2)
+
+	// the old leader comes back as a follower and catches up
+	servers[leader].Restore()
+	waitForFileVersion(t, servers[leader], "a.txt", 2)
+}
+
+func TestRaftRepairsTheLogOfADeposedLeader(t *testing.T) {
+	servers, network := newTestRaftClusterWithNetwork(t, 3)
+	leader := waitForLeader(t, servers)
+	updateTestFile(t, servers[leader], "a.txt", 1)
+
+	// cut off, the old leader takes an update it can never commit
+	network.Disconnect(leader)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*RAFT_ELECTION_TIMEOUT)
+	_, err := servers[leader].UpdateFile(ctx, &FileMetaData{Filename: "a.txt", Version: 2, BlockHashList: []string{"lost"}})
+	cancel()
+	if err == nil {
+		t.Fatal("a leader cut off from the others committed an update")
+	}
+
+	newLeader := waitForLeader(t, servers, others(servers, leader)...)
+	updateTestFile(t, servers[newLeader], "a.txt", 2)
+
+	// back on the network, the entry only the old leader has is replaced by the committed one
+	network.Heal()
+	waitForFileVersion(t, servers[leader], "a.txt", 2)
+	fileInfoMap, _ := servers[leader].MetaStore.GetFileInfoMap(context.Background(), &emptypb.Empty{})
+	if hashes := fileInfoMap.FileInfoMap["a.txt"].BlockHashList; len(hashes) != 1 || hashes[0] != "a.txt-2" {
+		t.Fatalf("the old leader applied %v, the entry it never committed", hashes)
+	}
+}
+
+func TestRaftMinorityCannotCommitOrRead(t *testing.T) {
+	servers, network := newTestRaftClusterWithNetwork(t, 5)
+	leader := waitForLeader(t, servers)
+	updateTestFile(t, servers[leader], "a.txt", 1)
+
+	follower := others(servers, leader)[0]
+	majority := make([]int64, 0)
+	for id := range servers {
+		if int64(id) != leader && int64(id) != follower {
+			majority = append(majority, int64(id))
+		}
+	}
+	network.Partition([]int64{leader, follower}, majority)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*RAFT_ELECTION_TIMEOUT)
+	defer cancel()
+	if _, err := servers[leader].UpdateFile(ctx, &FileMetaData{Filename: "b.txt", Version: 1, BlockHashList: []string{"b"}}); err == nil {
+		t.Fatal("the minority committed an update")
+	}
+	if _, err := servers[leader].GetFileInfoMap(ctx, &emptypb.Empty{}); err == nil {
+		t.Fatal("the minority served a read")
+	}
+
+	// the majority goes on without it
+	newLeader := waitForLeader(t, servers, majority...)
+	updateTestFile(t, servers[newLeader], "c.txt", 1)
+	if _, err := servers[newLeader].GetFileInfoMap(testContext(t), &emptypb.Empty{}); err != nil {
+		t.Fatalf("the majority cannot serve reads: %v", err)
+	}
+
+	network.Heal()
+	waitForFileVersion(t, servers[leader], "c.txt", 1)
+	waitForFileVersion(t, servers[follower], "c.txt", 1)
+	fileInfoMap, _ := servers[leader].MetaStore.GetFileInfoMap(context.Background(), &emptypb.Empty{})
+	if _, ok := fileInfoMap.FileInfoMap["b.txt"]; ok {
+		t.Fatal("the update the minority took was applied")
+	}
+}
+
+func TestRaftServersAgreeOnTheRingOfTheFirstLeader(t *testing.T) {
+	servers := newTestRaftCluster(t, 3)
+	// every server resolved other node IDs, as if the block stores had answered each one differently
+	for id, server := range servers {
+		blockStores := &BlockStoreAddrs{
+			BlockStoreAddrs: []string{"a:1", "b:1"},
+			NodeIds:         map[string]string{"a:1": fmt.Sprintf("a-%d", id), "b:1": fmt.Sprintf("b-%d", id)},
+		}
+		if err := server.MetaStore.SetBlockStores(blockStores); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leader := waitForLeader(t, servers)
+	if _, err := servers[leader].GetFileInfoMap(testContext(t), &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("a-%d", leader)
+	deadline := time.Now().Add(raftTestTimeout)
+	for id, server := range servers {
+		for server.MetaStore.blockStores().config.NodeIds["a:1"] != want || !server.HasBlockStores() {
+			if time.Now().After(deadline) {
+				t.Fatalf("server %d places a:1 as %s, the leader as %s", id, server.MetaStore.blockStores().config.NodeIds["a:1"], want)
+			}
+			time.Sleep(RAFT_HEARTBEAT_INTERVAL)
+		}
+	}
+
+	// a later leader keeps the ring in the log
+	servers[leader].Crash()
+	newLeader := waitForLeader(t, servers, others(servers, leader)...)
+	updateTestFile(t, servers[newLeader], "a.txt", 1)
+	if got := servers[newLeader].MetaStore.blockStores().config.NodeIds["a:1"]; got != want {
+		t.Fatalf("the next leader places a:1 as %s, not %s", got, want)
+	}
+}
+
+func TestRaftCatchesUpAFarBehindFollowerInBoundedCalls(t *testing.T) {
+	// a cluster like NewMemoryRaftCluster's, whose calls are measured
+	network := &MemoryRaftNetwork{Servers: make([]*RaftSurfstore, 3), cut: make(map[[2]int64]bool)}
+	measured := &measuredRaftTransport{}
+	for id := range network.Servers {
+		measured.transports = append(measured.transports, network.Transport(int64(id)))
+	}
+	for id := range network.Servers {
+		network.Servers[id], _ = NewRaftSurfstore(int64(id), 3, NewMetaStore([]string{}), measured.from(int64(id)), "")
+	}
+	for _, server := range network.Servers {
+		server.Start()
+	}
+	servers := network.Servers
+	t.Cleanup(func() {
+		for _, server := range servers {
+			server.Stop()
+		}
+	})
+
+	leader := waitForLeader(t, servers)
+	behind := others(servers, leader)[0]
+	servers[behind].Crash()
+	// more entries than one call carries, and more bytes
+	for i := 0; i < RAFT_MAX_APPEND_ENTRIES+50; i++ {
+		updateTestFile(t, servers[leader], fmt.Sprintf("small-%d", i), 1)
+	}
+	big := make([]string, 2000)
+	for i := range big {
+		big[i] = GetBlockHashString([]byte(fmt.Sprint(i)))
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := servers[leader].UpdateFile(testContext(t), &FileMetaData{Filename: fmt.Sprintf("big-%d", i), Version: 1, BlockHashList: big}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	servers[behind].Restore()
+	waitForFileVersion(t, servers[behind], "big-9", 1)
+	entries, size := measured.largest()
+	if entries > RAFT_MAX_APPEND_ENTRIES {
+		t.Fatalf("an AppendEntries carried %d entries, at most %d should go at once", entries, RAFT_MAX_APPEND_ENTRIES)
+	}
+	if size > RAFT_MAX_APPEND_BYTES+proto.Size(&UpdateOperation{FileMetaData: &FileMetaData{Filename: "big-9", Version: 1, BlockHashList: big}}) {
+		t.Fatalf("an AppendEntries carried %d bytes of entries, more than %d and one entry", size, RAFT_MAX_APPEND_BYTES)
+	}
+}
+
+// measuredRaftTransport remembers the largest AppendEntries sent through it
+type measuredRaftTransport struct {
+	transports []RaftTransport
+	mu         sync.Mutex
+	entries    int
+	size       int
+}
+
+func (m *measuredRaftTransport) from(id int64) RaftTransport {
+	return &measuredRaftSender{measured: m, transport: m.transports[id]}
+}
+
+func (m *measuredRaftTransport) largest() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries, m.size
+}
+
+type measuredRaftSender struct {
+	measured  *measuredRaftTransport
+	transport RaftTransport
+}
+
+func (s *measuredRaftSender) AppendEntries(ctx context.Context, serverId int64, input *AppendEntryInput) (*AppendEntryOutput, error) {
+	size := 0
+	for _, entry := range input.Entries {
+		size += proto.Size(entry)
+	}
+	s.measured.mu.Lock()
+	s.measured.entries = max(s.measured.entries, len(input.Entries))
+	s.measured.size = max(s.measured.size, size)
+	s.measured.mu.Unlock()
+	return s.transport.AppendEntries(ctx, serverId, input)
+}
+
+func (s *measuredRaftSender) RequestVote(ctx context.Context, serverId int64, input *RequestVoteInput) (*RequestVoteOutput, error) {
+	return s.transport.RequestVote(ctx, serverId, input)
+}
+
+func newTestRaftCluster(t *testing.T, numServers int64) []*RaftSurfstore {
+	servers, _ := newTestRaftClusterWithNetwork(t, numServers)
+	return servers
+}
+
+func newTestRaftClusterWithNetwork(t *testing.T, numServers int64) ([]*RaftSurfstore, *MemoryRaftNetwork) {
+	servers, network := NewMemoryRaftCluster(numServers, []string{})
+	t.Cleanup(func() {
+		for _, server := range servers {
+			server.Stop()
+		}
+	})
+	return servers, network
+}
+
+// waitForLeader waits until exactly one of the servers with the ids is the leader, all of them if none are given
+func waitForLeader(t *testing.T, servers []*RaftSurfstore, ids ...int64) int64 {
+	t.Helper()
+	if len(ids) == 0 {
+		for id := range servers {
+			ids = append(ids, int64(id))
+		}
+	}
+	deadline := time.Now().Add(raftTestTimeout)
+	for time.Now().Before(deadline) {
+		leaders := make([]int64, 0)
+		for _, id := range ids {
+			if servers[id].IsLeader() {
+				leaders = append(leaders, id)
+			}
+		}
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(RAFT_HEARTBEAT_INTERVAL)
+	}
+	t.Fatalf("no single leader among servers %v after %v", ids, raftTestTimeout)
+	return -1
+}
+
+// others lists the ids of every server but id
+func others(servers []*RaftSurfstore, id int64) []int64 {
+	ids := make([]int64, 0, len(servers)-1)
+	for other := range servers {
+		if int64(other) != id {
+			ids = append(ids, int64(other))
+		}
+	}
+	return ids
+}
+
+// updateTestFile commits version of filename, with one block named after both
+func updateTestFile(t *testing.T, server *RaftSurfstore, filename string, version int32) {
+	t.Helper()
+	fileMetaData := &FileMetaData{Filename: filename, Version: version, BlockHashList: []string{fmt.Sprintf("%s-%d", filename, version)}}
+	result, err := server.UpdateFile(testContext(t), fileMetaData)
+	if err != nil {
+		t.Fatalf("cannot update %s to version %d: %v", filename, version, err)
+	}
+	if result.Version != version {
+		t.Fatalf("updating %s to version %d returned version %d", filename, version, result.Version)
+	}
+}
+
+// waitForFileVersion waits until server has applied version of filename
+func waitForFileVersion(t *testing.T, server *RaftSurfstore, filename string, version int32) {
+	t.Helper()
+	deadline := time.Now().Add(raftTestTimeout)
+	for time.Now().Before(deadline) {
+		fileInfoMap, _ := server.MetaStore.GetFileInfoMap(context.Background(), &emptypb.Empty{})
+		if fileInfoMap.FileInfoMap[filename].GetVersion() == version {
+			return
+		}
+		time.Sleep(RAFT_HEARTBEAT_INTERVAL)
+	}
+	t.Fatalf("server %d did not apply version %d of %s after %v", server.Id, version, filename, raftTestTimeout)
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), raftTestTimeout)
+	t.Cleanup(cancel)
+	return ctx
+}
@@ -0,0 +1,93 @@
+package surfstore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RaftStorage keeps the state a raft server must not forget across a restart:
+// the current term, who it voted for, and its log
+type RaftStorage struct {
+	Dir string
+	wal *WriteAheadLog
+}
+
+const raftStateFilename string = "raft.state"
+const raftWalFilename string = "raft.wal"
+
+// OpenRaftStorage opens (or creates) the raft state in dir and returns what was saved there
+func OpenRaftStorage(dir string) (*RaftStorage, *RaftState, []*UpdateOperation, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, nil, err
+	}
+	state := &RaftState{Term: 0, VotedFor: -1}
+	data, err := os.ReadFile(filepath.Join(dir, raftStateFilename))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil, err
+	}
+	if err == nil {
+		if err := proto.Unmarshal(data, state); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	wal, records, err := OpenWriteAheadLog(filepath.Join(dir, raftWalFilename))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	entries := make([]*UpdateOperation, 0, len(records))
+	for _, record := range records {
+		entry := &UpdateOperation{}
+		if err := proto.Unmarshal(record, entry); err != nil {
+			wal.Close()
+			return nil, nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return &RaftStorage{Dir: dir, wal: wal}, state, entries, nil
+}
+
+func (s *RaftStorage) SaveState(term int64, votedFor int64) error {
+	data, err := proto.Marshal(&RaftState{Term: term, VotedFor: votedFor})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.Dir, raftStateFilename), data)
+}
+
+// AppendEntries adds entries to the end of the saved log
+func (s *RaftStorage) AppendEntries(entries []*UpdateOperation) error {
+	records, err := marshalRaftEntries(entries)
+	if err != nil {
+		return err
+	}
+	return s.wal.Append(records...)
+}
+
+// ReplaceLog saves log as the whole log, used when a follower drops conflicting entries
+func (s *RaftStorage) ReplaceLog(log []*UpdateOperation) error {
+	records, err := marshalRaftEntries(log)
+	if err != nil {
+		return err
+	}
+	return s.wal.Rewrite(records)
+}
+
+func (s *RaftStorage) Close() error {
+	return s.wal.Close()
+}
+
+func marshalRaftEntries(entries []*UpdateOperation) ([][]byte, error) {
+	records := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		record, err := proto.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
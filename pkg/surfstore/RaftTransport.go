@@ -0,0 +1,164 @@
+package surfstore
+
+import (
+	context "context"
+	"errors"
+	"sync"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// GrpcRaftTransport sends raft messages over grpc, server i listens on Addrs[i]
+type GrpcRaftTransport struct {
+	Addrs []string
+	mu    sync.Mutex
+	conns map[int64]*grpc.ClientConn
+}
+
+func (t *GrpcRaftTransport) AppendEntries(ctx context.Context, serverId int64, input *AppendEntryInput) (*AppendEntryOutput, error) {
+	conn, err := t.conn(serverId)
+	if err != nil {
+		return nil, err
+	}
+	return NewRaftSurfstoreClient(conn).AppendEntries(ctx, input)
+}
+
+func (t *GrpcRaftTransport) RequestVote(ctx context.Context, serverId int64, input *RequestVoteInput) (*RequestVoteOutput, error) {
+	conn, err := t.conn(serverId)
+	if err != nil {
+		return nil, err
+	}
+	return NewRaftSurfstoreClient(conn).RequestVote(ctx, input)
+}
+
+// conn keeps one connection per server, heartbeats are too frequent to dial every time
+func (t *GrpcRaftTransport) conn(serverId int64) (*grpc.ClientConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[serverId]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(t.Addrs[serverId], grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	t.conns[serverId] = conn
+	return conn, nil
+}
+
+// This line guarantees all method for GrpcRaftTransport are implemented
+var _ RaftTransport = new(GrpcRaftTransport)
+
+func NewGrpcRaftTransport(addrs []string) *GrpcRaftTransport {
+	return &GrpcRaftTransport{
+		Addrs: addrs,
+		conns: make(map[int64]*grpc.ClientConn),
+	}
+}
+
+var ErrPartitioned = errors.New("servers are partitioned")
+
+// MemoryRaftNetwork connects the servers of a cluster running in one process.
+// Messages are plain method calls, and any pair of servers can be cut off from each other.
+type MemoryRaftNetwork struct {
+	mu      sync.RWMutex
+	Servers []*RaftSurfstore
+	cut     map[[2]int64]bool
+}
+
+// Transport returns the transport server `from` uses to reach the others
+func (n *MemoryRaftNetwork) Transport(from int64) RaftTransport {
+	return &memoryRaftTransport{network: n, from: from}
+}
+
+// Partition splits the servers into groups that can only talk within the group
+func (n *MemoryRaftNetwork) Partition(groups ...[]int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	group := make(map[int64]int)
+	for i, ids := range groups {
+		for _, id := range ids {
+			group[id] = i + 1
+		}
+	}
+	n.cut = make(map[[2]int64]bool)
+	for a := range n.Servers {
+		for b := range n.Servers {
+			if group[int64(a)] != group[int64(b)] {
+				n.cut[[2]int64{int64(a), int64(b)}] = true
+			}
+		}
+	}
+}
+
+// Disconnect cuts one server off from every other server
+func (n *MemoryRaftNetwork) Disconnect(id int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for other := range n.Servers {
+		if int64(other) != id {
+			n.cut[[2]int64{id, int64(other)}] = true
+			n.cut[[2]int64{int64(other), id}] = true
+		}
+	}
+}
+
+// Heal reconnects every server
+func (n *MemoryRaftNetwork) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cut = make(map[[2]int64]bool)
+}
+
+func (n *MemoryRaftNetwork) connected(from int64, to int64) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return !n.cut[[2]int64{from, to}]
+}
+
+type memoryRaftTransport struct {
+	network *MemoryRaftNetwork
+	from    int64
+}
+
+func (t *memoryRaftTransport) AppendEntries(ctx context.Context, serverId int64, input *AppendEntryInput) (*AppendEntryOutput, error) {
+	if !t.network.connected(t.from, serverId) {
+		return nil, ErrPartitioned
+	}
+	output, err := t.network.Servers[serverId].AppendEntries(ctx, input)
+	// the reply travels back over the same link
+	if err == nil && !t.network.connected(serverId, t.from) {
+		return nil, ErrPartitioned
+	}
+	return output, err
+}
+
+func (t *memoryRaftTransport) RequestVote(ctx context.Context, serverId int64, input *RequestVoteInput) (*RequestVoteOutput, error) {
+	if !t.network.connected(t.from, serverId) {
+		return nil, ErrPartitioned
+	}
+	output, err := t.network.Servers[serverId].RequestVote(ctx, input)
+	if err == nil && !t.network.connected(serverId, t.from) {
+		return nil, ErrPartitioned
+	}
+	return output, err
+}
+
+// NewMemoryRaftCluster starts numServers raft servers in this process, connected by a MemoryRaftNetwork.
+// Each one applies to its own MetaStore configured with blockStoreAddrs.
+func NewMemoryRaftCluster(numServers int64, blockStoreAddrs []string) ([]*RaftSurfstore, *MemoryRaftNetwork) {
+	network := &MemoryRaftNetwork{
+		Servers: make([]*RaftSurfstore, numServers),
+		cut:     make(map[[2]int64]bool),
+	}
+	for id := int64(0); id < numServers; id++ {
+		// without a data directory NewRaftSurfstore cannot fail
+		server, _ := NewRaftSurfstore(id, numServers, NewMetaStore(blockStoreAddrs), network.Transport(id), "")
+		network.Servers[id] = server
+	}
+	for _, server := range network.Servers {
+		server.Start()
+	}
+	return network.Servers, network
+}
@@ -0,0 +1,214 @@
+package surfstore
+
+import (
+	"fmt"
+)
+
+// Reed-Solomon erasure coding over GF(2^8): k data shards and m parity shards of the same length,
+// any k of the k+m shards rebuild the data. The coding matrix is a (k+m) x k Vandermonde matrix
+// times the inverse of its top k rows, so the top k rows are the identity (the data shards are
+// stored as they are) and every k rows of it are still invertible.
+
+// GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1
+var gfExp [512]byte
+var gfLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
+
+// gfPow is a^n, with 0^0 = 1
+func gfPow(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]*n%255]
+}
+
+type reedSolomon struct {
+	dataShards   int
+	parityShards int
+	// (dataShards+parityShards) x dataShards, row i makes shard i out of the data shards
+	matrix [][]byte
+}
+
+func newReedSolomon(dataShards int, parityShards int) (*reedSolomon, error) {
+	if dataShards < 1 || parityShards < 1 || dataShards+parityShards > 256 {
+		return nil, fmt.Errorf("cannot code %d data and %d parity shards, at most 256 shards", dataShards, parityShards)
+	}
+	shards := dataShards + parityShards
+	vandermonde := make([][]byte, shards)
+	for i := range vandermonde {
+		vandermonde[i] = make([]byte, dataShards)
+		for j := range vandermonde[i] {
+			vandermonde[i][j] = gfPow(byte(i), j)
+		}
+	}
+	top, err := invertMatrix(vandermonde[:dataShards])
+	if err != nil {
+		return nil, err
+	}
+	return &reedSolomon{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		matrix:       multiplyMatrices(vandermonde, top),
+	}, nil
+}
+
+// Encode returns the parity shards of data, which has dataShards shards of the same length
+func (rs *reedSolomon) Encode(data [][]byte) ([][]byte, error) {
+	if len(data) != rs.dataShards {
+		return nil, fmt.Errorf("got %d data shards, want %d", len(data), rs.dataShards)
+	}
+	for _, shard := range data {
+		if len(shard) != len(data[0]) {
+			return nil, fmt.Errorf("data shards differ in length")
+		}
+	}
+	parity := make([][]byte, rs.parityShards)
+	for i := range parity {
+		parity[i] = make([]byte, len(data[0]))
+		codeShard(rs.matrix[rs.dataShards+i], data, parity[i])
+	}
+	return parity, nil
+}
+
+// Reconstruct fills in the missing (nil) data shards of shards, data shards first and parity shards
+// after them, out of any dataShards shards that are there. Missing parity shards stay missing
+func (rs *reedSolomon) Reconstruct(shards [][]byte) error {
+	if len(shards) != rs.dataShards+rs.parityShards {
+		return fmt.Errorf("got %d shards, want %d", len(shards), rs.dataShards+rs.parityShards)
+	}
+	missing := false
+	for _, shard := range shards[:rs.dataShards] {
+		missing = missing || shard == nil
+	}
+	if !missing {
+		return nil
+	}
+	// the rows of the first dataShards shards there are
+	rows := make([][]byte, 0, rs.dataShards)
+	present := make([][]byte, 0, rs.dataShards)
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if len(present) > 0 && len(shard) != len(present[0]) {
+			return fmt.Errorf("shards differ in length")
+		}
+		rows = append(rows, rs.matrix[i])
+		present = append(present, shard)
+		if len(present) == rs.dataShards {
+			break
+		}
+	}
+	if len(present) < rs.dataShards {
+		return fmt.Errorf("only %d of the %d shards needed are there", len(present), rs.dataShards)
+	}
+	// present = rows x data, so data = rows^-1 x present
+	decode, err := invertMatrix(rows)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < rs.dataShards; i++ {
+		if shards[i] == nil {
+			shards[i] = make([]byte, len(present[0]))
+			codeShard(decode[i], present, shards[i])
+		}
+	}
+	return nil
+}
+
+// codeShard sets out to the sum of coefficients[j] * shards[j]
+func codeShard(coefficients []byte, shards [][]byte, out []byte) {
+	for j, c := range coefficients {
+		if c == 0 {
+			continue
+		}
+		logC := gfLog[c]
+		for b, x := range shards[j] {
+			if x != 0 {
+				out[b] ^= gfExp[logC+gfLog[x]]
+			}
+		}
+	}
+}
+
+func multiplyMatrices(a [][]byte, b [][]byte) [][]byte {
+	product := make([][]byte, len(a))
+	for i := range a {
+		product[i] = make([]byte, len(b[0]))
+		for j := range b[0] {
+			var sum byte
+			for k := range b {
+				sum ^= gfMul(a[i][k], b[k][j])
+			}
+			product[i][j] = sum
+		}
+	}
+	return product
+}
+
+// invertMatrix inverts a square matrix by Gauss-Jordan elimination
+func invertMatrix(matrix [][]byte) ([][]byte, error) {
+	n := len(matrix)
+	work := make([][]byte, n)
+	for i := range work {
+		work[i] = make([]byte, 2*n)
+		copy(work[i], matrix[i])
+		work[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, fmt.Errorf("matrix is singular")
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+		scale := gfInv(work[col][col])
+		for j := range work[col] {
+			work[col][j] = gfMul(work[col][j], scale)
+		}
+		for i := 0; i < n; i++ {
+			if i == col || work[i][col] == 0 {
+				continue
+			}
+			factor := work[i][col]
+			for j := range work[i] {
+				work[i][j] ^= gfMul(factor, work[col][j])
+			}
+		}
+	}
+	inverse := make([][]byte, n)
+	for i := range inverse {
+		inverse[i] = work[i][n:]
+	}
+	return inverse, nil
+}
@@ -1,6 +1,14 @@
 package surfstore
 
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
 const DEFAULT_META_FILENAME string = "index.db"
+const DEFAULT_JOURNAL_FILENAME string = "index.journal"
 
 const TOMBSTONE_HASHVALUE string = "0"
 const EMPTYFILE_HASHVALUE string = "-1"
@@ -11,3 +19,95 @@ const HASH_LIST_INDEX int = 2
 
 const CONFIG_DELIMITER string = ","
 const HASH_DELIMITER string = " "
+
+const STORAGE_MEMORY string = "memory"
+const STORAGE_DISK string = "disk"
+
+const DEFAULT_SNAPSHOT_EVERY int = 1000
+
+// how the MetaStore places blocks on the block servers, see PlacementStrategy
+const PLACEMENT_CONSISTENT string = "consistent"
+const PLACEMENT_RENDEZVOUS string = "rendezvous"
+const PLACEMENT_BOUNDED string = "bounded"
+
+const DEFAULT_PLACEMENT string = PLACEMENT_CONSISTENT
+
+// a block server takes at most 1+BOUNDED_LOAD_EPSILON times its fair share of the ring
+// with bounded-load placement
+const BOUNDED_LOAD_EPSILON float64 = 0.25
+
+// points on the consistent hash ring per unit of block server weight.
+// 1 keeps the placement of a ring without virtual nodes, changing it moves most blocks
+const DEFAULT_VIRTUAL_NODES int = 1
+
+// how many block servers keep a copy of each block
+const DEFAULT_REPLICATION_FACTOR int = 1
+
+// how many bytes of stripe shards a client erasure codes before putting them on the block servers
+const ERASURE_UPLOAD_BUFFER int = 64 << 20
+
+// the largest block a BlockStore takes by default, which is also the largest message grpc takes by default
+const DEFAULT_MAX_BLOCK_SIZE int = 4 << 20
+
+// how many hashes a client puts in one GetBlockStoreMap or MissingBlocks request
+const HASH_BATCH_SIZE int = 10000
+
+// how many received blocks a download keeps per block server before it stops reading the stream
+const BLOCK_STREAM_BUFFER int = 8
+
+// blocks written or touched within the grace period survive garbage collection,
+// they may belong to an upload that has not called UpdateFile yet
+const DEFAULT_GC_GRACE_PERIOD time.Duration = time.Hour
+
+// how long one garbage collection may take, the sweep walks every block of a server
+const GC_TIMEOUT time.Duration = 10 * time.Minute
+
+// how long adding or removing a block server may take, its blocks are copied in the meantime
+const MIGRATION_TIMEOUT time.Duration = 30 * time.Minute
+
+// how many times the blocks uploaded to the old replicas during a migration are copied over before
+// the migration fails, and how long it waits in between
+const MIGRATION_CATCH_UP_ATTEMPTS int = 3
+const MIGRATION_CATCH_UP_RETRY time.Duration = time.Second
+
+// how often the MetaStore probes every block server, and how long a block server may go without
+// answering before it is suspect, then down
+const HEALTH_CHECK_INTERVAL time.Duration = time.Second
+const BLOCKSTORE_SUSPECT_TIMEOUT time.Duration = 3 * time.Second
+const BLOCKSTORE_DOWN_TIMEOUT time.Duration = 10 * time.Second
+
+// the status of a block server in GetClusterStatus, unknown when the MetaStore does not probe
+const BLOCKSTORE_UP string = "up"
+const BLOCKSTORE_SUSPECT string = "suspect"
+const BLOCKSTORE_DOWN string = "down"
+const BLOCKSTORE_UNKNOWN string = "unknown"
+
+// how often a block server compares its blocks with the other replicas, and how long a peer may take
+// to answer a comparison
+const ANTI_ENTROPY_INTERVAL time.Duration = time.Minute
+const ANTI_ENTROPY_TIMEOUT time.Duration = time.Minute
+
+const RAFT_HEARTBEAT_INTERVAL time.Duration = 50 * time.Millisecond
+const RAFT_ELECTION_TIMEOUT time.Duration = 300 * time.Millisecond
+
+// the most log entries, and bytes of them, one AppendEntries carries. An entry larger than that
+// still goes, on its own
+const RAFT_MAX_APPEND_ENTRIES int = 512
+const RAFT_MAX_APPEND_BYTES int = 1 << 20
+
+// a file modified this close to the scan that hashed it may change again without its modification
+// time changing (file systems keep it in ticks as coarse as 2 seconds), so its stat data is not
+// trusted and it is hashed again on the next sync
+const STAT_RACE_WINDOW time.Duration = 2 * time.Second
+
+// how many files a client hashes, and how many block servers it uploads to, at the same time
+const DEFAULT_CLIENT_JOBS int = 4
+
+// how many times a client walks through the MetaStore addresses looking for the leader
+const META_RETRY_ROUNDS int = 10
+
+var ERR_SERVER_CRASHED = status.Error(codes.Unavailable, "server is crashed")
+var ERR_NOT_LEADER = status.Error(codes.FailedPrecondition, "server is not the leader")
+
+// a leader that loses the lead before its update is committed cannot tell whether it will be
+var ERR_LEADERSHIP_LOST = status.Error(codes.Unavailable, "server lost the lead, the update may still be committed")
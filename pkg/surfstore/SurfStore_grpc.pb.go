@@ -20,10 +20,17 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	BlockStore_GetBlock_FullMethodName       = "/surfstore.BlockStore/GetBlock"
-	BlockStore_PutBlock_FullMethodName       = "/surfstore.BlockStore/PutBlock"
-	BlockStore_MissingBlocks_FullMethodName  = "/surfstore.BlockStore/MissingBlocks"
-	BlockStore_GetBlockHashes_FullMethodName = "/surfstore.BlockStore/GetBlockHashes"
+	BlockStore_GetBlock_FullMethodName        = "/surfstore.BlockStore/GetBlock"
+	BlockStore_PutBlock_FullMethodName        = "/surfstore.BlockStore/PutBlock"
+	BlockStore_MissingBlocks_FullMethodName   = "/surfstore.BlockStore/MissingBlocks"
+	BlockStore_GetBlockHashes_FullMethodName  = "/surfstore.BlockStore/GetBlockHashes"
+	BlockStore_PutBlocks_FullMethodName       = "/surfstore.BlockStore/PutBlocks"
+	BlockStore_GetBlocks_FullMethodName       = "/surfstore.BlockStore/GetBlocks"
+	BlockStore_SweepBlocks_FullMethodName     = "/surfstore.BlockStore/SweepBlocks"
+	BlockStore_GetNodeId_FullMethodName       = "/surfstore.BlockStore/GetNodeId"
+	BlockStore_GetMerkleTree_FullMethodName   = "/surfstore.BlockStore/GetMerkleTree"
+	BlockStore_GetRangeHashes_FullMethodName  = "/surfstore.BlockStore/GetRangeHashes"
+	BlockStore_GetRepairStatus_FullMethodName = "/surfstore.BlockStore/GetRepairStatus"
 )
 
 // BlockStoreClient is the client API for BlockStore service.
@@ -34,6 +41,13 @@ type BlockStoreClient interface {
 	PutBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Success, error)
 	MissingBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockHashes, error)
 	GetBlockHashes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockHashes, error)
+	PutBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_PutBlocksClient, error)
+	GetBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (BlockStore_GetBlocksClient, error)
+	SweepBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_SweepBlocksClient, error)
+	GetNodeId(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeId, error)
+	GetMerkleTree(ctx context.Context, in *MerkleTreeRequest, opts ...grpc.CallOption) (*MerkleTree, error)
+	GetRangeHashes(ctx context.Context, in *MerkleTreeRequest, opts ...grpc.CallOption) (*BlockHashes, error)
+	GetRepairStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RepairStatus, error)
 }
 
 type blockStoreClient struct {
@@ -80,6 +94,142 @@ func (c *blockStoreClient) GetBlockHashes(ctx context.Context, in *emptypb.Empty
 	return out, nil
 }
 
+func (c *blockStoreClient) PutBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_PutBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockStore_ServiceDesc.Streams[0], BlockStore_PutBlocks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockStorePutBlocksClient{stream}
+	return x, nil
+}
+
+type BlockStore_PutBlocksClient interface {
+	Send(*Block) error
+	CloseAndRecv() (*Success, error)
+	grpc.ClientStream
+}
+
+type blockStorePutBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockStorePutBlocksClient) Send(m *Block) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *blockStorePutBlocksClient) CloseAndRecv() (*Success, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Success)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockStoreClient) GetBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (BlockStore_GetBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockStore_ServiceDesc.Streams[1], BlockStore_GetBlocks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockStoreGetBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BlockStore_GetBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type blockStoreGetBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockStoreGetBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockStoreClient) SweepBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_SweepBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockStore_ServiceDesc.Streams[2], BlockStore_SweepBlocks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockStoreSweepBlocksClient{stream}
+	return x, nil
+}
+
+type BlockStore_SweepBlocksClient interface {
+	Send(*LiveBlocks) error
+	CloseAndRecv() (*GarbageCollectionReport, error)
+	grpc.ClientStream
+}
+
+type blockStoreSweepBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockStoreSweepBlocksClient) Send(m *LiveBlocks) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *blockStoreSweepBlocksClient) CloseAndRecv() (*GarbageCollectionReport, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(GarbageCollectionReport)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockStoreClient) GetNodeId(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeId, error) {
+	out := new(NodeId)
+	err := c.cc.Invoke(ctx, BlockStore_GetNodeId_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockStoreClient) GetMerkleTree(ctx context.Context, in *MerkleTreeRequest, opts ...grpc.CallOption) (*MerkleTree, error) {
+	out := new(MerkleTree)
+	err := c.cc.Invoke(ctx, BlockStore_GetMerkleTree_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockStoreClient) GetRangeHashes(ctx context.Context, in *MerkleTreeRequest, opts ...grpc.CallOption) (*BlockHashes, error) {
+	out := new(BlockHashes)
+	err := c.cc.Invoke(ctx, BlockStore_GetRangeHashes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockStoreClient) GetRepairStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RepairStatus, error) {
+	out := new(RepairStatus)
+	err := c.cc.Invoke(ctx, BlockStore_GetRepairStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BlockStoreServer is the server API for BlockStore service.
 // All implementations must embed UnimplementedBlockStoreServer
 // for forward compatibility
@@ -88,6 +238,13 @@ type BlockStoreServer interface {
 	PutBlock(context.Context, *Block) (*Success, error)
 	MissingBlocks(context.Context, *BlockHashes) (*BlockHashes, error)
 	GetBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error)
+	PutBlocks(BlockStore_PutBlocksServer) error
+	GetBlocks(*BlockHashes, BlockStore_GetBlocksServer) error
+	SweepBlocks(BlockStore_SweepBlocksServer) error
+	GetNodeId(context.Context, *emptypb.Empty) (*NodeId, error)
+	GetMerkleTree(context.Context, *MerkleTreeRequest) (*MerkleTree, error)
+	GetRangeHashes(context.Context, *MerkleTreeRequest) (*BlockHashes, error)
+	GetRepairStatus(context.Context, *emptypb.Empty) (*RepairStatus, error)
 	mustEmbedUnimplementedBlockStoreServer()
 }
 
@@ -107,6 +264,27 @@ func (UnimplementedBlockStoreServer) MissingBlocks(context.Context, *BlockHashes
 func (UnimplementedBlockStoreServer) GetBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlockHashes not implemented")
 }
+func (UnimplementedBlockStoreServer) PutBlocks(BlockStore_PutBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method PutBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) GetBlocks(*BlockHashes, BlockStore_GetBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) SweepBlocks(BlockStore_SweepBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method SweepBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) GetNodeId(context.Context, *emptypb.Empty) (*NodeId, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeId not implemented")
+}
+func (UnimplementedBlockStoreServer) GetMerkleTree(context.Context, *MerkleTreeRequest) (*MerkleTree, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMerkleTree not implemented")
+}
+func (UnimplementedBlockStoreServer) GetRangeHashes(context.Context, *MerkleTreeRequest) (*BlockHashes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRangeHashes not implemented")
+}
+func (UnimplementedBlockStoreServer) GetRepairStatus(context.Context, *emptypb.Empty) (*RepairStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRepairStatus not implemented")
+}
 func (UnimplementedBlockStoreServer) mustEmbedUnimplementedBlockStoreServer() {}
 
 // UnsafeBlockStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -192,6 +370,151 @@ func _BlockStore_GetBlockHashes_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockStore_PutBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BlockStoreServer).PutBlocks(&blockStorePutBlocksServer{stream})
+}
+
+type BlockStore_PutBlocksServer interface {
+	SendAndClose(*Success) error
+	Recv() (*Block, error)
+	grpc.ServerStream
+}
+
+type blockStorePutBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockStorePutBlocksServer) SendAndClose(m *Success) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *blockStorePutBlocksServer) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BlockStore_GetBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockHashes)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockStoreServer).GetBlocks(m, &blockStoreGetBlocksServer{stream})
+}
+
+type BlockStore_GetBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type blockStoreGetBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockStoreGetBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _BlockStore_SweepBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BlockStoreServer).SweepBlocks(&blockStoreSweepBlocksServer{stream})
+}
+
+type BlockStore_SweepBlocksServer interface {
+	SendAndClose(*GarbageCollectionReport) error
+	Recv() (*LiveBlocks, error)
+	grpc.ServerStream
+}
+
+type blockStoreSweepBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockStoreSweepBlocksServer) SendAndClose(m *GarbageCollectionReport) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *blockStoreSweepBlocksServer) Recv() (*LiveBlocks, error) {
+	m := new(LiveBlocks)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BlockStore_GetNodeId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).GetNodeId(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockStore_GetNodeId_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).GetNodeId(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockStore_GetMerkleTree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MerkleTreeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).GetMerkleTree(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockStore_GetMerkleTree_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).GetMerkleTree(ctx, req.(*MerkleTreeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockStore_GetRangeHashes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MerkleTreeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).GetRangeHashes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockStore_GetRangeHashes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).GetRangeHashes(ctx, req.(*MerkleTreeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockStore_GetRepairStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).GetRepairStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockStore_GetRepairStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).GetRepairStatus(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BlockStore_ServiceDesc is the grpc.ServiceDesc for BlockStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -215,8 +538,40 @@ var BlockStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockHashes",
 			Handler:    _BlockStore_GetBlockHashes_Handler,
 		},
+		{
+			MethodName: "GetNodeId",
+			Handler:    _BlockStore_GetNodeId_Handler,
+		},
+		{
+			MethodName: "GetMerkleTree",
+			Handler:    _BlockStore_GetMerkleTree_Handler,
+		},
+		{
+			MethodName: "GetRangeHashes",
+			Handler:    _BlockStore_GetRangeHashes_Handler,
+		},
+		{
+			MethodName: "GetRepairStatus",
+			Handler:    _BlockStore_GetRepairStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PutBlocks",
+			Handler:       _BlockStore_PutBlocks_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetBlocks",
+			Handler:       _BlockStore_GetBlocks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SweepBlocks",
+			Handler:       _BlockStore_SweepBlocks_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/surfstore/SurfStore.proto",
 }
 
@@ -225,6 +580,12 @@ const (
 	MetaStore_UpdateFile_FullMethodName         = "/surfstore.MetaStore/UpdateFile"
 	MetaStore_GetBlockStoreMap_FullMethodName   = "/surfstore.MetaStore/GetBlockStoreMap"
 	MetaStore_GetBlockStoreAddrs_FullMethodName = "/surfstore.MetaStore/GetBlockStoreAddrs"
+	MetaStore_CollectGarbage_FullMethodName     = "/surfstore.MetaStore/CollectGarbage"
+	MetaStore_AddBlockStore_FullMethodName      = "/surfstore.MetaStore/AddBlockStore"
+	MetaStore_RemoveBlockStore_FullMethodName   = "/surfstore.MetaStore/RemoveBlockStore"
+	MetaStore_SetBlockStoreAddr_FullMethodName  = "/surfstore.MetaStore/SetBlockStoreAddr"
+	MetaStore_GetClusterStatus_FullMethodName   = "/surfstore.MetaStore/GetClusterStatus"
+	MetaStore_RegisterBlockStore_FullMethodName = "/surfstore.MetaStore/RegisterBlockStore"
 )
 
 // MetaStoreClient is the client API for MetaStore service.
@@ -235,6 +596,12 @@ type MetaStoreClient interface {
 	UpdateFile(ctx context.Context, in *FileMetaData, opts ...grpc.CallOption) (*Version, error)
 	GetBlockStoreMap(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockStoreMap, error)
 	GetBlockStoreAddrs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockStoreAddrs, error)
+	CollectGarbage(ctx context.Context, in *GarbageCollection, opts ...grpc.CallOption) (*GarbageCollectionReport, error)
+	AddBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error)
+	RemoveBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error)
+	SetBlockStoreAddr(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*Success, error)
+	GetClusterStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterStatus, error)
+	RegisterBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error)
 }
 
 type metaStoreClient struct {
@@ -281,6 +648,60 @@ func (c *metaStoreClient) GetBlockStoreAddrs(ctx context.Context, in *emptypb.Em
 	return out, nil
 }
 
+func (c *metaStoreClient) CollectGarbage(ctx context.Context, in *GarbageCollection, opts ...grpc.CallOption) (*GarbageCollectionReport, error) {
+	out := new(GarbageCollectionReport)
+	err := c.cc.Invoke(ctx, MetaStore_CollectGarbage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) AddBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error) {
+	out := new(MigrationReport)
+	err := c.cc.Invoke(ctx, MetaStore_AddBlockStore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) RemoveBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error) {
+	out := new(MigrationReport)
+	err := c.cc.Invoke(ctx, MetaStore_RemoveBlockStore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) SetBlockStoreAddr(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*Success, error) {
+	out := new(Success)
+	err := c.cc.Invoke(ctx, MetaStore_SetBlockStoreAddr_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetClusterStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterStatus, error) {
+	out := new(ClusterStatus)
+	err := c.cc.Invoke(ctx, MetaStore_GetClusterStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) RegisterBlockStore(ctx context.Context, in *BlockStoreAddr, opts ...grpc.CallOption) (*MigrationReport, error) {
+	out := new(MigrationReport)
+	err := c.cc.Invoke(ctx, MetaStore_RegisterBlockStore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetaStoreServer is the server API for MetaStore service.
 // All implementations must embed UnimplementedMetaStoreServer
 // for forward compatibility
@@ -289,6 +710,12 @@ type MetaStoreServer interface {
 	UpdateFile(context.Context, *FileMetaData) (*Version, error)
 	GetBlockStoreMap(context.Context, *BlockHashes) (*BlockStoreMap, error)
 	GetBlockStoreAddrs(context.Context, *emptypb.Empty) (*BlockStoreAddrs, error)
+	CollectGarbage(context.Context, *GarbageCollection) (*GarbageCollectionReport, error)
+	AddBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error)
+	RemoveBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error)
+	SetBlockStoreAddr(context.Context, *BlockStoreAddr) (*Success, error)
+	GetClusterStatus(context.Context, *emptypb.Empty) (*ClusterStatus, error)
+	RegisterBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error)
 	mustEmbedUnimplementedMetaStoreServer()
 }
 
@@ -308,6 +735,24 @@ func (UnimplementedMetaStoreServer) GetBlockStoreMap(context.Context, *BlockHash
 func (UnimplementedMetaStoreServer) GetBlockStoreAddrs(context.Context, *emptypb.Empty) (*BlockStoreAddrs, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlockStoreAddrs not implemented")
 }
+func (UnimplementedMetaStoreServer) CollectGarbage(context.Context, *GarbageCollection) (*GarbageCollectionReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CollectGarbage not implemented")
+}
+func (UnimplementedMetaStoreServer) AddBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBlockStore not implemented")
+}
+func (UnimplementedMetaStoreServer) RemoveBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBlockStore not implemented")
+}
+func (UnimplementedMetaStoreServer) SetBlockStoreAddr(context.Context, *BlockStoreAddr) (*Success, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBlockStoreAddr not implemented")
+}
+func (UnimplementedMetaStoreServer) GetClusterStatus(context.Context, *emptypb.Empty) (*ClusterStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClusterStatus not implemented")
+}
+func (UnimplementedMetaStoreServer) RegisterBlockStore(context.Context, *BlockStoreAddr) (*MigrationReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterBlockStore not implemented")
+}
 func (UnimplementedMetaStoreServer) mustEmbedUnimplementedMetaStoreServer() {}
 
 // UnsafeMetaStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -393,6 +838,114 @@ func _MetaStore_GetBlockStoreAddrs_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetaStore_CollectGarbage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GarbageCollection)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).CollectGarbage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_CollectGarbage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).CollectGarbage(ctx, req.(*GarbageCollection))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_AddBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreAddr)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).AddBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_AddBlockStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).AddBlockStore(ctx, req.(*BlockStoreAddr))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_RemoveBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreAddr)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).RemoveBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_RemoveBlockStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).RemoveBlockStore(ctx, req.(*BlockStoreAddr))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_SetBlockStoreAddr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreAddr)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).SetBlockStoreAddr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_SetBlockStoreAddr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).SetBlockStoreAddr(ctx, req.(*BlockStoreAddr))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetClusterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_GetClusterStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetClusterStatus(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_RegisterBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreAddr)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).RegisterBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaStore_RegisterBlockStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).RegisterBlockStore(ctx, req.(*BlockStoreAddr))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetaStore_ServiceDesc is the grpc.ServiceDesc for MetaStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -416,6 +969,157 @@ var MetaStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockStoreAddrs",
 			Handler:    _MetaStore_GetBlockStoreAddrs_Handler,
 		},
+		{
+			MethodName: "CollectGarbage",
+			Handler:    _MetaStore_CollectGarbage_Handler,
+		},
+		{
+			MethodName: "AddBlockStore",
+			Handler:    _MetaStore_AddBlockStore_Handler,
+		},
+		{
+			MethodName: "RemoveBlockStore",
+			Handler:    _MetaStore_RemoveBlockStore_Handler,
+		},
+		{
+			MethodName: "SetBlockStoreAddr",
+			Handler:    _MetaStore_SetBlockStoreAddr_Handler,
+		},
+		{
+			MethodName: "GetClusterStatus",
+			Handler:    _MetaStore_GetClusterStatus_Handler,
+		},
+		{
+			MethodName: "RegisterBlockStore",
+			Handler:    _MetaStore_RegisterBlockStore_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/surfstore/SurfStore.proto",
+}
+
+const (
+	RaftSurfstore_AppendEntries_FullMethodName = "/surfstore.RaftSurfstore/AppendEntries"
+	RaftSurfstore_RequestVote_FullMethodName   = "/surfstore.RaftSurfstore/RequestVote"
+)
+
+// RaftSurfstoreClient is the client API for RaftSurfstore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RaftSurfstoreClient interface {
+	AppendEntries(ctx context.Context, in *AppendEntryInput, opts ...grpc.CallOption) (*AppendEntryOutput, error)
+	RequestVote(ctx context.Context, in *RequestVoteInput, opts ...grpc.CallOption) (*RequestVoteOutput, error)
+}
+
+type raftSurfstoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaftSurfstoreClient(cc grpc.ClientConnInterface) RaftSurfstoreClient {
+	return &raftSurfstoreClient{cc}
+}
+
+func (c *raftSurfstoreClient) AppendEntries(ctx context.Context, in *AppendEntryInput, opts ...grpc.CallOption) (*AppendEntryOutput, error) {
+	out := new(AppendEntryOutput)
+	err := c.cc.Invoke(ctx, RaftSurfstore_AppendEntries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftSurfstoreClient) RequestVote(ctx context.Context, in *RequestVoteInput, opts ...grpc.CallOption) (*RequestVoteOutput, error) {
+	out := new(RequestVoteOutput)
+	err := c.cc.Invoke(ctx, RaftSurfstore_RequestVote_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RaftSurfstoreServer is the server API for RaftSurfstore service.
+// All implementations must embed UnimplementedRaftSurfstoreServer
+// for forward compatibility
+type RaftSurfstoreServer interface {
+	AppendEntries(context.Context, *AppendEntryInput) (*AppendEntryOutput, error)
+	RequestVote(context.Context, *RequestVoteInput) (*RequestVoteOutput, error)
+	mustEmbedUnimplementedRaftSurfstoreServer()
+}
+
+// UnimplementedRaftSurfstoreServer must be embedded to have forward compatible implementations.
+type UnimplementedRaftSurfstoreServer struct {
+}
+
+func (UnimplementedRaftSurfstoreServer) AppendEntries(context.Context, *AppendEntryInput) (*AppendEntryOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendEntries not implemented")
+}
+func (UnimplementedRaftSurfstoreServer) RequestVote(context.Context, *RequestVoteInput) (*RequestVoteOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestVote not implemented")
+}
+func (UnimplementedRaftSurfstoreServer) mustEmbedUnimplementedRaftSurfstoreServer() {}
+
+// UnsafeRaftSurfstoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaftSurfstoreServer will
+// result in compilation errors.
+type UnsafeRaftSurfstoreServer interface {
+	mustEmbedUnimplementedRaftSurfstoreServer()
+}
+
+func RegisterRaftSurfstoreServer(s grpc.ServiceRegistrar, srv RaftSurfstoreServer) {
+	s.RegisterService(&RaftSurfstore_ServiceDesc, srv)
+}
+
+func _RaftSurfstore_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendEntryInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftSurfstoreServer).AppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftSurfstore_AppendEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftSurfstoreServer).AppendEntries(ctx, req.(*AppendEntryInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftSurfstore_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestVoteInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftSurfstoreServer).RequestVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RaftSurfstore_RequestVote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftSurfstoreServer).RequestVote(ctx, req.(*RequestVoteInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RaftSurfstore_ServiceDesc is the grpc.ServiceDesc for RaftSurfstore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RaftSurfstore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "surfstore.RaftSurfstore",
+	HandlerType: (*RaftSurfstoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AppendEntries",
+			Handler:    _RaftSurfstore_AppendEntries_Handler,
+		},
+		{
+			MethodName: "RequestVote",
+			Handler:    _RaftSurfstore_RequestVote_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/surfstore/SurfStore.proto",
@@ -5,9 +5,9 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -29,6 +29,67 @@ func ConcatPath(baseDir, fileDir string) string {
 	return baseDir + "/" + fileDir
 }
 
+/* Durable File Writes Related */
+
+// temp files of writeFileAtomic are hidden and share this prefix, so they can be told apart from
+// the files they replace
+const tempFilePrefix string = ".tmp-"
+
+// downloads land in temp files with this prefix in the base directory. The client keeps the prefix
+// to itself: files named like it are never synced
+const downloadFilePrefix string = ".surfstore-download-"
+
+// isDownloadLeftover tells a temp file os.CreateTemp made for the download of one of the indexed
+// files, which a download that did not finish leaves behind
+func isDownloadLeftover(name string, localFileInfoMap map[string]*FileMetaData) bool {
+	// CreateTemp puts its random digits after the last dash of the pattern
+	rest := strings.TrimPrefix(name, downloadFilePrefix)
+	i := strings.LastIndex(rest, "-")
+	if i < 0 {
+		return false
+	}
+	_, indexed := localFileInfoMap[rest[:i]]
+	random := rest[i+1:]
+	return indexed && random != "" && strings.Trim(random, "0123456789") == ""
+}
+
+// writeFileAtomic writes data to a temp file, fsyncs it and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, tempFilePrefix+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file 0600, give it the usual permissions
+	if err = tmp.Chmod(0644); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
 /*
 	Writing Local Metadata File Related
 */
@@ -40,121 +101,329 @@ const createTable string = `create table if not exists indexes (
 		hashValue TEXT
 	);`
 
+// the schema version of index.db is the number of indexMigrations applied to it
+const createSchemaVersionTable string = `create table if not exists schema_version (version INT);`
+
+// indexMigrations take index.db from schema version i to i+1. Every one runs in its own transaction,
+// with the new schema version. A database from before schema versions has no schema_version table,
+// it is taken to be at version 0, which is why the first migration must be harmless on it
+var indexMigrations = []string{
+	// 1: the table every client had before schema versions
+	createTable,
+	// 2: one row per block of a file
+	`create unique index if not exists indexes_block on indexes (fileName, hashIndex);`,
+	// 3: the stat data of every file when it was hashed, see FileStat
+	`create table if not exists file_stats (fileName TEXT primary key, size INT, modTime INT, inode INT);`,
+}
+
+// openMetaFile opens (or creates) index.db and migrates it to the latest schema version
+func openMetaFile(metaFilePath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", metaFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateMetaFile(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot migrate %s: %w", metaFilePath, err)
+	}
+	return db, nil
+}
+
+func migrateMetaFile(db *sql.DB) error {
+	version, err := metaSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > len(indexMigrations) {
+		return fmt.Errorf("schema version %d is newer than this client, which knows up to %d", version, len(indexMigrations))
+	}
+	for ; version < len(indexMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range []string{createSchemaVersionTable, indexMigrations[version], `delete from schema_version;`} {
+			if _, err = tx.Exec(statement); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			_, err = tx.Exec(`insert into schema_version (version) values (?);`, version+1)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration to schema version %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+// metaSchemaVersion is 0 for a database without a schema_version table
+func metaSchemaVersion(db *sql.DB) (int, error) {
+	var tables int
+	err := db.QueryRow(`select count(*) from sqlite_master where type = 'table' and name = 'schema_version';`).Scan(&tables)
+	if err != nil || tables == 0 {
+		return 0, err
+	}
+	var version int
+	err = db.QueryRow(`select version from schema_version;`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
 // insert into: put a new tuple into the table(indexes)
 // (?, ?, ?, ?) are placeholders for the values of the tuple
 const insertTuple string = `insert into indexes (fileName, version, hashIndex, hashValue) VALUES (?, ?, ?, ?);`
 
-// WriteMetaFile writes the file meta map back to local metadata file index.db
+// WriteMetaFile writes the file meta map back to local metadata file index.db.
+// The old rows go and the new ones come in one transaction, a crash leaves either index in place
 func WriteMetaFile(fileMetas map[string]*FileMetaData, baseDir string) error {
-	// remove index.db file if it exists
-	outputMetaPath := ConcatPath(baseDir, DEFAULT_META_FILENAME)
-	if _, err := os.Stat(outputMetaPath); err == nil {
-		e := os.Remove(outputMetaPath)
-		if e != nil {
-			log.Fatal("Error During Meta Write Back")
-		}
-	}
-	db, err := sql.Open("sqlite3", outputMetaPath)
+	return WriteMetaFileWithStats(fileMetas, nil, baseDir)
+}
+
+// WriteMetaFileWithStats is WriteMetaFile that also replaces the stat data of the files with stats,
+// in the same transaction. The stat data is left as it is if stats is nil
+func WriteMetaFileWithStats(fileMetas map[string]*FileMetaData, stats map[string]*FileStat, baseDir string) error {
+	db, err := openMetaFile(ConcatPath(baseDir, DEFAULT_META_FILENAME))
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
 	}
-	statement, err := db.Prepare(createTable)
+	defer db.Close()
+	tx, err := db.Begin()
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
+	}
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+	if _, err := tx.Exec(`delete from indexes;`); err != nil {
+		return err
 	}
-	statement.Exec()
-	//panic("todo")
-	statement, err = db.Prepare(insertTuple)
+	statement, err := tx.Prepare(insertTuple)
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
 	}
+	defer statement.Close()
 	// The table has 4 columns which are fileName, version, hashIndex, hashValue.
 	// Their types are TEXT, INT, INT, and TEXT respectively
 	for fileName, filemeta := range fileMetas {
 		for hashIndex, hashValue := range filemeta.BlockHashList { // Index should start from 0
-			statement.Exec(fileName, filemeta.Version, hashIndex, hashValue)
+			if _, err := statement.Exec(fileName, filemeta.Version, hashIndex, hashValue); err != nil {
+				return fmt.Errorf("cannot write %s to the index: %w", fileName, err)
+			}
 		}
 	}
-	statement.Close()
-	db.Close()
-	return nil
+	if stats != nil {
+		if _, err := tx.Exec(`delete from file_stats;`); err != nil {
+			return err
+		}
+		for fileName, stat := range stats {
+			// sqlite has no unsigned integers, the inode goes in as its bits
+			if _, err := tx.Exec(insertFileStat, fileName, stat.Size, stat.ModTime, int64(stat.Inode)); err != nil {
+				return fmt.Errorf("cannot write %s to the index: %w", fileName, err)
+			}
+		}
+	}
+	return tx.Commit()
 }
 
+const insertFileStat string = `insert into file_stats (fileName, size, modTime, inode) VALUES (?, ?, ?, ?);`
+
 /*
 Reading Local Metadata File Related
 */
-const getDistinctFileName string = `select distinct fileName, version from indexes;`
 
 // asc: ascending order
-const getTuplesByFileName string = `select fileName, version, hashIndex, hashValue from indexes where fileName=? AND version=? order by hashIndex ASC
-`
+const getTuples string = `select fileName, version, hashIndex, hashValue from indexes order by fileName ASC, hashIndex ASC;`
 
 // LoadMetaFromMetaFile loads the local metadata file into a file meta map.
 // The key is the file's name and the value is the file's metadata.
 // You can use this function to load the index.db file in this project.
 func LoadMetaFromMetaFile(baseDir string) (fileMetaMap map[string]*FileMetaData, e error) {
-	//fmt.Println("Loading Meta From Meta File")
 	metaFilePath, _ := filepath.Abs(ConcatPath(baseDir, DEFAULT_META_FILENAME))
-	//fmt.Println("Meta File Path: ", metaFilePath)
 	fileMetaMap = make(map[string]*FileMetaData)
-	//fmt.Println("File Meta Map: ", fileMetaMap)
 	metaFileStats, e := os.Stat(metaFilePath)
 	if e != nil || metaFileStats.IsDir() {
 		return fileMetaMap, nil
 	}
-	db, err := sql.Open("sqlite3", metaFilePath)
-	db = db // remove warning
+	db, err := openMetaFile(metaFilePath)
 	if err != nil {
-		log.Fatal("Error When Opening Meta")
+		return nil, err
 	}
-	//panic("todo")
+	defer db.Close()
 
-	statement, err := db.Prepare(createTable)
+	rows, err := db.Query(getTuples)
 	if err != nil {
-		log.Fatalf("Error create Table: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fileName string
+		var version int32
+		var hashIndex int
+		var hashValue string
+		if err := rows.Scan(&fileName, &version, &hashIndex, &hashValue); err != nil {
+			return nil, err
+		}
+		if _, ok := fileMetaMap[fileName]; !ok {
+			fileMetaMap[fileName] = &FileMetaData{
+				Filename:      fileName,
+				Version:       version,
+				BlockHashList: []string{},
+			}
+		}
+		fileMetaMap[fileName].BlockHashList = append(fileMetaMap[fileName].BlockHashList, hashValue)
 	}
+	return fileMetaMap, rows.Err()
+}
 
-	if _, err = statement.Exec(); err != nil {
-		log.Fatalf("Error create Table: %v", err)
+const getFileStats string = `select fileName, size, modTime, inode from file_stats;`
+
+// LoadFileStats loads the stat data of the files in the local metadata file, by file name
+func LoadFileStats(baseDir string) (map[string]*FileStat, error) {
+	metaFilePath, _ := filepath.Abs(ConcatPath(baseDir, DEFAULT_META_FILENAME))
+	stats := make(map[string]*FileStat)
+	metaFileStats, err := os.Stat(metaFilePath)
+	if err != nil || metaFileStats.IsDir() {
+		return stats, nil
+	}
+	db, err := openMetaFile(metaFilePath)
+	if err != nil {
+		return nil, err
 	}
-	statement.Close()
+	defer db.Close()
 
-	rows, err := db.Query(getDistinctFileName) // get all distinct file names
+	rows, err := db.Query(getFileStats)
 	if err != nil {
-		log.Fatal("Error while querying distinct file names", err)
+		return nil, err
 	}
 	defer rows.Close()
-
 	for rows.Next() {
 		var fileName string
-		var version int32
-		if err := rows.Scan(&fileName, &version); err != nil {
-			log.Fatal("Error while scanning distinct file names1", err)
+		var inode int64
+		stat := &FileStat{}
+		if err := rows.Scan(&fileName, &stat.Size, &stat.ModTime, &inode); err != nil {
+			return nil, err
 		}
-		hashValues := []string{}
-		hashRows, err := db.Query(getTuplesByFileName, fileName, version)
-		defer hashRows.Close()
-		if err != nil {
-			log.Fatal("Error while scanning distinct file names2", err)
-		}
-		for hashRows.Next() {
-			var fileName string
-			var version int
-			var hashIndex int
-			var hashValue string
-			if err := hashRows.Scan(&fileName, &version, &hashIndex, &hashValue); err != nil {
-				log.Fatal("Error while scanning distinct file names3", err)
+		stat.Inode = uint64(inode)
+		stats[fileName] = stat
+	}
+	return stats, rows.Err()
+}
+
+// isIndexFile tells the files the client keeps its own state in, index.db with the files sqlite
+// keeps next to it and the sync journal, apart from the files it syncs
+func isIndexFile(name string) bool {
+	switch name {
+	case DEFAULT_META_FILENAME, DEFAULT_META_FILENAME + "-journal", DEFAULT_META_FILENAME + "-wal", DEFAULT_META_FILENAME + "-shm", DEFAULT_JOURNAL_FILENAME:
+		return true
+	}
+	return false
+}
+
+/*
+	Checking Local Metadata File Related
+*/
+
+// CheckMetaFile checks the index.db in baseDir without changing it. It returns the schema version
+// and what is wrong with the contents, nothing if the index is sound
+func CheckMetaFile(baseDir string) (version int, problems []string, err error) {
+	metaFilePath, _ := filepath.Abs(ConcatPath(baseDir, DEFAULT_META_FILENAME))
+	if _, err := os.Stat(metaFilePath); err != nil {
+		return 0, nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+metaFilePath+"?mode=ro")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`pragma integrity_check;`).Scan(&result); err != nil {
+		return 0, nil, err
+	}
+	if result != "ok" {
+		problems = append(problems, "sqlite integrity check: "+result)
+	}
+	if version, err = metaSchemaVersion(db); err != nil {
+		return 0, nil, err
+	}
+	if version > len(indexMigrations) {
+		problems = append(problems, fmt.Sprintf("schema version %d is newer than this client, which knows up to %d", version, len(indexMigrations)))
+	}
+	var tables int
+	if err := db.QueryRow(`select count(*) from sqlite_master where type = 'table' and name = 'indexes';`).Scan(&tables); err != nil {
+		return version, nil, err
+	}
+	if tables == 0 {
+		return version, append(problems, "no indexes table"), nil
+	}
+
+	rows, err := db.Query(getTuples)
+	if err != nil {
+		return version, nil, err
+	}
+	defer rows.Close()
+	// the rows of one file at a time, they come ordered by file name
+	var fileName string
+	var versions map[int64]bool
+	var hashValues []string
+	var lastHashIndex int64
+	checkFile := func() {
+		if hashValues == nil {
+			return
+		}
+		if len(versions) > 1 {
+			problems = append(problems, fmt.Sprintf("%s: rows of %d different versions", fileName, len(versions)))
+		}
+		for version := range versions {
+			if version < 1 {
+				problems = append(problems, fmt.Sprintf("%s: version %d", fileName, version))
 			}
-			hashValues = append(hashValues, hashValue)
 		}
-
-		fileMetaMap[fileName] = &FileMetaData{
-			Filename:      fileName,
-			Version:       version,
-			BlockHashList: hashValues,
+		for i, hashValue := range hashValues {
+			switch {
+			case hashValue == TOMBSTONE_HASHVALUE || hashValue == EMPTYFILE_HASHVALUE:
+				if len(hashValues) != 1 {
+					problems = append(problems, fmt.Sprintf("%s: block %d is %q, which must be the only block", fileName, i, hashValue))
+				}
+			case !isBlockHash(hashValue):
+				problems = append(problems, fmt.Sprintf("%s: block %d has hash %q", fileName, i, hashValue))
+			}
 		}
 	}
-	return fileMetaMap, nil
+	for rows.Next() {
+		var rowFileName sql.NullString
+		var rowVersion, hashIndex sql.NullInt64
+		var hashValue sql.NullString
+		if err := rows.Scan(&rowFileName, &rowVersion, &hashIndex, &hashValue); err != nil {
+			return version, nil, err
+		}
+		if !rowFileName.Valid || !rowVersion.Valid || !hashIndex.Valid || !hashValue.Valid {
+			problems = append(problems, fmt.Sprintf("%s: row with a NULL column", rowFileName.String))
+			continue
+		}
+		if hashValues == nil || rowFileName.String != fileName {
+			checkFile()
+			fileName, versions, hashValues, lastHashIndex = rowFileName.String, map[int64]bool{}, []string{}, -1
+		}
+		// hashIndex counts the blocks of a file from 0 without gaps
+		if hashIndex.Int64 != lastHashIndex+1 {
+			problems = append(problems, fmt.Sprintf("%s: hashIndex %d follows %d", fileName, hashIndex.Int64, lastHashIndex))
+		}
+		lastHashIndex = hashIndex.Int64
+		versions[rowVersion.Int64] = true
+		hashValues = append(hashValues, hashValue.String)
+	}
+	if err := rows.Err(); err != nil {
+		return version, nil, err
+	}
+	checkFile()
+	return version, problems, nil
 }
 
 /*
@@ -0,0 +1,119 @@
+package surfstore
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeOldMetaFile writes an index.db with only the first version migrations applied, and rows of
+// (fileName, version, hashIndex, hashValue). At version 0 it has no schema_version table, like the
+// index of a client from before schema versions
+func writeOldMetaFile(t *testing.T, baseDir string, version int, rows [][]any) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(baseDir, DEFAULT_META_FILENAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	statements := []string{createTable}
+	if version > 0 {
+		statements = append(append(statements, indexMigrations[:version]...), createSchemaVersionTable, fmt.Sprintf(`insert into schema_version (version) values (%d);`, version))
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, row := range rows {
+		if _, err := db.Exec(insertTuple, row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOldIndexesMigrateToTheCurrentSchema(t *testing.T) {
+	a, b := GetBlockHashString([]byte("a")), GetBlockHashString([]byte("b"))
+	for version := 0; version < len(indexMigrations); version++ {
+		baseDir := t.TempDir()
+		writeOldMetaFile(t, baseDir, version, [][]any{{"a.txt", 2, 0, a}, {"a.txt", 2, 1, b}, {"gone.txt", 3, 0, TOMBSTONE_HASHVALUE}})
+		if got, problems, err := CheckMetaFile(baseDir); err != nil || got != version || len(problems) != 0 {
+			t.Fatalf("version %d: checked as version %d, problems %v, error %v", version, got, problems, err)
+		}
+
+		fileMetaMap, err := LoadMetaFromMetaFile(baseDir)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if len(fileMetaMap) != 2 || fileMetaMap["a.txt"].Version != 2 || !CompareBlockHashList(fileMetaMap["a.txt"].BlockHashList, []string{a, b}) || fileMetaMap["gone.txt"].BlockHashList[0] != TOMBSTONE_HASHVALUE {
+			t.Fatalf("version %d: loaded %v", version, fileMetaMap)
+		}
+		if got, problems, err := CheckMetaFile(baseDir); err != nil || got != len(indexMigrations) || len(problems) != 0 {
+			t.Fatalf("version %d: checked as version %d after loading, problems %v, error %v", version, got, problems, err)
+		}
+		// the stat data came with the migration, and the index is written back at the current version
+		stats := map[string]*FileStat{"a.txt": {Size: 2, ModTime: 1, Inode: 3}}
+		if err := WriteMetaFileWithStats(fileMetaMap, stats, baseDir); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if loaded, err := LoadFileStats(baseDir); err != nil || loaded["a.txt"] == nil || *loaded["a.txt"] != *stats["a.txt"] {
+			t.Fatalf("version %d: loaded stats %v, error %v", version, loaded, err)
+		}
+	}
+}
+
+func TestCheckMetaFileFindsCorruptIndexes(t *testing.T) {
+	a, b := GetBlockHashString([]byte("a")), GetBlockHashString([]byte("b"))
+	for _, test := range []struct {
+		name string
+		rows [][]any
+		want string
+	}{
+		{"gap", [][]any{{"a.txt", 1, 0, a}, {"a.txt", 1, 2, b}}, "hashIndex 2 follows 0"},
+		{"versions", [][]any{{"a.txt", 1, 0, a}, {"a.txt", 2, 1, b}}, "rows of 2 different versions"},
+		{"version 0", [][]any{{"a.txt", 0, 0, a}}, "version 0"},
+		{"bad hash", [][]any{{"a.txt", 1, 0, "not a hash"}}, `has hash "not a hash"`},
+		{"tombstone among blocks", [][]any{{"a.txt", 1, 0, a}, {"a.txt", 1, 1, TOMBSTONE_HASHVALUE}}, "must be the only block"},
+		{"null", [][]any{{"a.txt", 1, 0, nil}}, "NULL column"},
+	} {
+		baseDir := t.TempDir()
+		writeOldMetaFile(t, baseDir, 1, test.rows)
+		_, problems, err := CheckMetaFile(baseDir)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(problems) != 1 || !strings.Contains(problems[0], test.want) {
+			t.Fatalf("%s: problems %q, want one with %q", test.name, problems, test.want)
+		}
+	}
+
+	// an index that is not a database at all
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, DEFAULT_META_FILENAME), []byte(strings.Repeat("not sqlite ", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, problems, err := CheckMetaFile(baseDir); err == nil && len(problems) == 0 {
+		t.Fatal("an index of garbage checked as sound")
+	}
+
+	// an index written by a newer client
+	baseDir = t.TempDir()
+	writeOldMetaFile(t, baseDir, len(indexMigrations), nil)
+	db, err := sql.Open("sqlite3", filepath.Join(baseDir, DEFAULT_META_FILENAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`update schema_version set version = ?;`, len(indexMigrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, problems, err := CheckMetaFile(baseDir); err != nil || len(problems) != 1 || !strings.Contains(problems[0], "newer than this client") {
+		t.Fatalf("problems %q, error %v with a newer schema version", problems, err)
+	}
+	if _, err := LoadMetaFromMetaFile(baseDir); err == nil {
+		t.Fatal("loaded an index with a newer schema version")
+	}
+}
@@ -2,6 +2,7 @@ package surfstore
 
 import (
 	context "context"
+	"time"
 
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
@@ -18,6 +19,24 @@ type MetaStoreInterface interface {
 
 	// Retrieve all BlockStore Addresses
 	GetBlockStoreAddrs(ctx context.Context, _ *emptypb.Empty) (*BlockStoreAddrs, error)
+
+	// Mark the blocks referenced by any file and have every BlockStore sweep the rest
+	CollectGarbage(ctx context.Context, gc *GarbageCollection) (*GarbageCollectionReport, error)
+
+	// Add a BlockStore to the ring, moving the blocks it is now responsible for onto it
+	AddBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error)
+
+	// Take a BlockStore off the ring, moving its blocks to the servers taking over
+	RemoveBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error)
+
+	// Change the address of the BlockStore with a node ID, no block moves
+	SetBlockStoreAddr(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*Success, error)
+
+	// Report whether every BlockStore answers the MetaStore's probes
+	GetClusterStatus(ctx context.Context, _ *emptypb.Empty) (*ClusterStatus, error)
+
+	// Add a starting BlockStore to the ring, or point the ring at its new address if it moved
+	RegisterBlockStore(ctx context.Context, blockStoreAddr *BlockStoreAddr) (*MigrationReport, error)
 }
 
 type BlockStoreInterface interface {
@@ -34,6 +53,77 @@ type BlockStoreInterface interface {
 
 	// Get which blocks are on this BlockStore server
 	GetBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error)
+
+	// Put every block sent on the stream
+	PutBlocks(stream BlockStore_PutBlocksServer) error
+
+	// Stream the blocks of the given hashes back, in order
+	GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error
+
+	// Delete every block not in the streamed live hashes and older than the grace period
+	SweepBlocks(stream BlockStore_SweepBlocksServer) error
+
+	// Get the node ID blocks are placed on this BlockStore by
+	GetNodeId(ctx context.Context, _ *emptypb.Empty) (*NodeId, error)
+
+	// Get the Merkle tree of the blocks this BlockStore should hold along with a peer
+	GetMerkleTree(ctx context.Context, request *MerkleTreeRequest) (*MerkleTree, error)
+
+	// List the blocks this BlockStore shares with a peer in some ranges of its Merkle tree,
+	// or every block it holds in them without a peer
+	GetRangeHashes(ctx context.Context, request *MerkleTreeRequest) (*BlockHashes, error)
+
+	// Report how anti-entropy is going on this BlockStore
+	GetRepairStatus(ctx context.Context, _ *emptypb.Empty) (*RepairStatus, error)
+}
+
+type RaftInterface interface {
+	// The MetaStore a client talks to, only served by the leader
+	MetaStoreInterface
+
+	// Replicate log entries from the leader, an empty list is a heartbeat
+	AppendEntries(ctx context.Context, input *AppendEntryInput) (*AppendEntryOutput, error)
+
+	// Ask for a vote during a leader election
+	RequestVote(ctx context.Context, input *RequestVoteInput) (*RequestVoteOutput, error)
+}
+
+// RaftTransport carries raft messages from one server to another server of the cluster
+type RaftTransport interface {
+	AppendEntries(ctx context.Context, serverId int64, input *AppendEntryInput) (*AppendEntryOutput, error)
+	RequestVote(ctx context.Context, serverId int64, input *RequestVoteInput) (*RequestVoteOutput, error)
+}
+
+// BlockStorage is where a BlockStore keeps its blocks, keyed by block hash
+type BlockStorage interface {
+	// Get a block, the bool is false if the hash is not stored
+	Get(hash string) (*Block, bool, error)
+
+	// Store a block under its hash
+	Put(hash string, block *Block) error
+
+	// Check whether a hash is stored
+	Has(hash string) (bool, error)
+
+	// List every stored hash
+	Hashes() ([]string, error)
+
+	// List the stored hashes starting with prefix
+	HashesWithPrefix(prefix string) ([]string, error)
+
+	// Size and last write time of a block, the bool is false if the hash is not stored
+	Stat(hash string) (BlockInfo, bool, error)
+
+	// Mark a block as just written, so garbage collection leaves it alone for a grace period
+	Touch(hash string) error
+
+	// Remove a block
+	Delete(hash string) error
+}
+
+type BlockInfo struct {
+	Size    int64
+	ModTime time.Time
 }
 
 type ClientInterface interface {
@@ -42,10 +132,26 @@ type ClientInterface interface {
 	UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error
 	GetBlockStoreMap(blockHashesIn []string, blockStoreMap *map[string][]string) error
 	GetBlockStoreAddrs(blockStoreAddrs *[]string) error
+	GetBlockStoreWeights(weights *map[string]int32) error
+	GetBlockStoreZones(zones *map[string]string) error
+	GetBlockStoreRing(blockStores *BlockStoreAddrs) error
+	CollectGarbage(gracePeriod time.Duration, report *GarbageCollectionReport) error
+	AddBlockStore(blockStoreAddr string, weight int32, zone string, report *MigrationReport) error
+	RemoveBlockStore(blockStoreAddr string, report *MigrationReport) error
+	SetBlockStoreAddr(nodeId string, blockStoreAddr string) error
+	GetClusterStatus(blockStores *[]*BlockStoreStatus) error
+	RegisterBlockStore(blockStoreAddr string, weight int32, zone string, report *MigrationReport) error
 
 	// BlockStore
 	GetBlock(blockHash string, blockStoreAddr string, block *Block) error
 	PutBlock(block *Block, blockStoreAddr string, succ *bool) error
 	MissingBlocks(blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error
 	GetBlockHashes(blockStoreAddr string, blockHashes *[]string) error
+	PutBlocks(blockStoreAddr string, nextBlock func() (*Block, error), succ *bool) error
+	GetBlocks(blockHashesIn []string, blockStoreAddr string, receiveBlock func(*Block) error) error
+	SweepBlocks(liveHashes []string, gracePeriod time.Duration, blockStoreAddr string, report *GarbageCollectionReport) error
+	GetNodeId(blockStoreAddr string, nodeId *string) error
+	GetMerkleTree(blockStoreAddr string, request *MerkleTreeRequest, nodes *[][]byte) error
+	GetRangeHashes(blockStoreAddr string, request *MerkleTreeRequest, blockHashes *[]string) error
+	GetRepairStatus(blockStoreAddr string, repairStatus *RepairStatus) error
 }
@@ -2,20 +2,31 @@ package surfstore
 
 import (
 	context "context"
-	"database/sql"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
 	"log"
-	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type RPCClient struct {
-	MetaStoreAddr string
-	BaseDir       string
-	BlockSize     int
+	// MetaStoreAddrs lists every server of the MetaStore cluster, the client finds the leader itself
+	MetaStoreAddrs []string
+	BaseDir        string
+	BlockSize      int
+	// Paranoid hashes every file on every sync, not only the files whose stat data changed
+	Paranoid bool
+	// Jobs is how many files are hashed, and how many block servers are uploaded to, at once. 0 is 1
+	Jobs int
+	// metaLeader is the index of the last MetaStore that answered, shared by copies of the client
+	metaLeader *atomic.Int64
 }
 
 func (surfClient *RPCClient) GetBlockHashes(blockStoreAddr string, blockHashes *[]string) error {
@@ -114,52 +125,246 @@ func (surfClient *RPCClient) MissingBlocks(blockHashesIn []string, blockStoreAdd
 	return conn.Close()
 }
 
-func (surfClient *RPCClient) GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error {
-	//panic("todo")
-	// connect to the server
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// PutBlocks streams blocks to one block server over a single connection.
+// nextBlock is called for each block to send and returns io.EOF when there are no more.
+func (surfClient *RPCClient) PutBlocks(blockStoreAddr string, nextBlock func() (*Block, error), succ *bool) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return err
 	}
-	// conn: to the meta store server
-	c := NewMetaStoreClient(conn)
-	// perform the call
+	defer conn.Close()
+	c := NewBlockStoreClient(conn)
+
+	// no deadline, the stream lasts as long as there are blocks
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.PutBlocks(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		block, err := nextBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// Send waits while the server is behind, so blocks are read only as fast as they are sent
+		if err := stream.Send(block); err != nil {
+			if err == io.EOF { // the server stopped the stream, the reason comes with CloseAndRecv
+				break
+			}
+			return err
+		}
+	}
+	success, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	*succ = success.Flag
+	return nil
+}
+
+// GetBlocks streams the blocks of blockHashesIn from one block server over a single connection.
+// receiveBlock is called for each block, in the order of blockHashesIn.
+func (surfClient *RPCClient) GetBlocks(blockHashesIn []string, blockStoreAddr string, receiveBlock func(*Block) error) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	c := NewBlockStoreClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.GetBlocks(ctx, &BlockHashes{Hashes: blockHashesIn})
+	if err != nil {
+		return err
+	}
+	for {
+		block, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := receiveBlock(block); err != nil {
+			return err
+		}
+	}
+}
+
+// SweepBlocks streams the live hashes to one block server in batches, then waits for its sweep report
+func (surfClient *RPCClient) SweepBlocks(liveHashes []string, gracePeriod time.Duration, blockStoreAddr string, report *GarbageCollectionReport) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	c := NewBlockStoreClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), GC_TIMEOUT)
+	defer cancel()
+	stream, err := c.SweepBlocks(ctx)
+	if err != nil {
+		return err
+	}
+	gracePeriodSeconds := int64(gracePeriod / time.Second)
+	for start := 0; start < len(liveHashes) || start == 0; start += HASH_BATCH_SIZE {
+		end := start + HASH_BATCH_SIZE
+		if end > len(liveHashes) {
+			end = len(liveHashes)
+		}
+		// an empty batch still carries the grace period
+		batch := &LiveBlocks{Hashes: liveHashes[start:end], GracePeriodSeconds: gracePeriodSeconds}
+		if err := stream.Send(batch); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+	r, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	*report = GarbageCollectionReport{BlocksScanned: r.BlocksScanned, BlocksDeleted: r.BlocksDeleted, BytesReclaimed: r.BytesReclaimed}
+	return nil
+}
+
+func (surfClient *RPCClient) GetNodeId(blockStoreAddr string, nodeId *string) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	c := NewBlockStoreClient(conn)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
+	n, err := c.GetNodeId(ctx, &emptypb.Empty{})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	*nodeId = n.Id
+	return conn.Close()
+}
+
+// GetMerkleTree gets the Merkle tree of the blocks a block server shares with request.Peer
+func (surfClient *RPCClient) GetMerkleTree(blockStoreAddr string, request *MerkleTreeRequest, nodes *[][]byte) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	c := NewBlockStoreClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), ANTI_ENTROPY_TIMEOUT)
+	defer cancel()
+	t, err := c.GetMerkleTree(ctx, request)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	*nodes = t.Nodes
+	return conn.Close()
+}
 
-	m, err := c.GetFileInfoMap(ctx, &emptypb.Empty{})
-	// fmt.Println("m: ", m)
-	// I don't know why in project4, multiple blockserver, m can be nil
+// GetRangeHashes lists the blocks a block server shares with request.Peer in request.Ranges,
+// every block it holds there if request.Peer is empty
+func (surfClient *RPCClient) GetRangeHashes(blockStoreAddr string, request *MerkleTreeRequest, blockHashes *[]string) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	c := NewBlockStoreClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), ANTI_ENTROPY_TIMEOUT)
+	defer cancel()
+	b, err := c.GetRangeHashes(ctx, request)
 	if err != nil {
 		conn.Close()
+		return err
 	}
-	*serverFileInfoMap = m.FileInfoMap
-	// close the connection
+	*blockHashes = b.Hashes
 	return conn.Close()
 }
 
-func (surfClient *RPCClient) UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error {
-	//panic("todo")
-	// connect to the server
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (surfClient *RPCClient) GetRepairStatus(blockStoreAddr string, repairStatus *RepairStatus) error {
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return err
 	}
-	// conn: to the meta store server
-	c := NewMetaStoreClient(conn)
-	// perform the call
+	c := NewBlockStoreClient(conn)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	m, err := c.UpdateFile(ctx, fileMetaData)
-	*latestVersion = m.Version
+	s, err := c.GetRepairStatus(ctx, &emptypb.Empty{})
 	if err != nil {
 		conn.Close()
 		return err
 	}
-	// close the connection
+	proto.Reset(repairStatus)
+	proto.Merge(repairStatus, s)
 	return conn.Close()
 }
 
+func (surfClient *RPCClient) GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		m, err := c.GetFileInfoMap(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		*serverFileInfoMap = m.FileInfoMap
+		return nil
+	})
+}
+
+// UpdateFile is not idempotent: sent twice, the second one finds the version taken. A server that is
+// not the leader did not take it, and the next one is asked. An update that timed out or lost its server
+// may have been committed all the same, so it is only sent again once the file is found without it
+func (surfClient *RPCClient) UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error {
+	for attempt := 1; ; attempt++ {
+		err := surfClient.metaCallRetrying(time.Second, notLeader, func(c MetaStoreClient, ctx context.Context) error {
+			m, err := c.UpdateFile(ctx, fileMetaData)
+			if err != nil {
+				return err
+			}
+			*latestVersion = m.Version
+			return nil
+		})
+		if err == nil {
+			if *latestVersion == -1 && attempt > 1 {
+				// maybe taken by the attempt we did not hear back from
+				if applied, checkErr := surfClient.updateApplied(fileMetaData); checkErr == nil && applied {
+					*latestVersion = fileMetaData.Version
+				}
+			}
+			return nil
+		}
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded:
+		default:
+			return err
+		}
+		if applied, checkErr := surfClient.updateApplied(fileMetaData); checkErr == nil && applied {
+			*latestVersion = fileMetaData.Version
+			return nil
+		}
+		if attempt == META_RETRY_ROUNDS {
+			return err
+		}
+		time.Sleep(RAFT_ELECTION_TIMEOUT)
+	}
+}
+
+// updateApplied tells whether the MetaStore has fileMetaData as the latest version of its file
+func (surfClient *RPCClient) updateApplied(fileMetaData *FileMetaData) (bool, error) {
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := surfClient.GetFileInfoMap(&fileInfoMap); err != nil {
+		return false, err
+	}
+	remote, ok := fileInfoMap[fileMetaData.Filename]
+	return ok && remote.Version == fileMetaData.Version && CompareBlockHashList(remote.BlockHashList, fileMetaData.BlockHashList), nil
+}
+
 //func (surfClient *RPCClient) GetBlockStoreAddr(blockStoreAddr *string) error {
 //	//panic("todo")
 //	// connect to the server
@@ -182,47 +387,208 @@ func (surfClient *RPCClient) UpdateFile(fileMetaData *FileMetaData, latestVersio
 //}
 
 func (surfClient *RPCClient) GetBlockStoreMap(blockHashesIn []string, blockStoreMap *map[string][]string) error {
-	//panic("todo")
-	// connect to the server
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		blockStoreMaptemp, err := c.GetBlockStoreMap(ctx, &BlockHashes{Hashes: blockHashesIn})
+		if err != nil {
+			return err
+		}
+		for k, v := range blockStoreMaptemp.BlockStoreMap {
+			(*blockStoreMap)[k] = v.Hashes
+		}
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetBlockStoreAddrs(blockStoreAddrs *[]string) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		m, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		*blockStoreAddrs = m.BlockStoreAddrs
+		return nil
+	})
+}
+
+// GetBlockStoreWeights gets the weight of every block server on the ring
+func (surfClient *RPCClient) GetBlockStoreWeights(weights *map[string]int32) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		m, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		*weights = map[string]int32{}
+		for _, addr := range m.BlockStoreAddrs {
+			(*weights)[addr] = 1
+			if weight, ok := m.Weights[addr]; ok && weight > 0 {
+				(*weights)[addr] = weight
+			}
+		}
+		return nil
+	})
+}
+
+// GetBlockStoreZones gets the zone of every block server on the ring that has one
+func (surfClient *RPCClient) GetBlockStoreZones(zones *map[string]string) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		m, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		*zones = map[string]string{}
+		for addr, zone := range m.Zones {
+			(*zones)[addr] = zone
+		}
+		return nil
+	})
+}
+
+// GetBlockStoreRing gets the whole configuration of the ring, with the replication factor
+func (surfClient *RPCClient) GetBlockStoreRing(blockStores *BlockStoreAddrs) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		m, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		proto.Reset(blockStores)
+		proto.Merge(blockStores, m)
+		return nil
+	})
+}
+
+// CollectGarbage asks the MetaStore to run a garbage collection over every block server
+func (surfClient *RPCClient) CollectGarbage(gracePeriod time.Duration, report *GarbageCollectionReport) error {
+	return surfClient.metaCallTimeout(GC_TIMEOUT, func(c MetaStoreClient, ctx context.Context) error {
+		r, err := c.CollectGarbage(ctx, &GarbageCollection{GracePeriodSeconds: int64(gracePeriod / time.Second)})
+		if err != nil {
+			return err
+		}
+		*report = GarbageCollectionReport{BlocksScanned: r.BlocksScanned, BlocksDeleted: r.BlocksDeleted, BytesReclaimed: r.BytesReclaimed}
+		return nil
+	})
+}
+
+// AddBlockStore asks the MetaStore to add a block server in zone (empty for none), it returns once the blocks are moved
+func (surfClient *RPCClient) AddBlockStore(blockStoreAddr string, weight int32, zone string, report *MigrationReport) error {
+	return surfClient.metaCallTimeout(MIGRATION_TIMEOUT, func(c MetaStoreClient, ctx context.Context) error {
+		r, err := c.AddBlockStore(ctx, &BlockStoreAddr{Addr: blockStoreAddr, Weight: weight, Zone: zone})
+		if err != nil {
+			return err
+		}
+		*report = MigrationReport{BlocksCopied: r.BlocksCopied, BytesCopied: r.BytesCopied}
+		return nil
+	})
+}
+
+// RegisterBlockStore tells the MetaStore the block server at blockStoreAddr has started, it returns once
+// the block server is on the ring with its blocks
+func (surfClient *RPCClient) RegisterBlockStore(blockStoreAddr string, weight int32, zone string, report *MigrationReport) error {
+	return surfClient.metaCallTimeout(MIGRATION_TIMEOUT, func(c MetaStoreClient, ctx context.Context) error {
+		r, err := c.RegisterBlockStore(ctx, &BlockStoreAddr{Addr: blockStoreAddr, Weight: weight, Zone: zone})
+		if err != nil {
+			return err
+		}
+		*report = MigrationReport{BlocksCopied: r.BlocksCopied, BytesCopied: r.BytesCopied}
+		return nil
+	})
+}
+
+// RemoveBlockStore asks the MetaStore to remove a block server, it returns once the blocks are moved
+func (surfClient *RPCClient) RemoveBlockStore(blockStoreAddr string, report *MigrationReport) error {
+	return surfClient.metaCallTimeout(MIGRATION_TIMEOUT, func(c MetaStoreClient, ctx context.Context) error {
+		r, err := c.RemoveBlockStore(ctx, &BlockStoreAddr{Addr: blockStoreAddr})
+		if err != nil {
+			return err
+		}
+		*report = MigrationReport{BlocksCopied: r.BlocksCopied, BytesCopied: r.BytesCopied}
+		return nil
+	})
+}
+
+// SetBlockStoreAddr tells the MetaStore the block server with nodeId moved to blockStoreAddr
+func (surfClient *RPCClient) SetBlockStoreAddr(nodeId string, blockStoreAddr string) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		_, err := c.SetBlockStoreAddr(ctx, &BlockStoreAddr{Addr: blockStoreAddr, NodeId: nodeId})
 		return err
+	})
+}
+
+// GetClusterStatus gets the status of every block server on the ring, as the MetaStore probes them
+func (surfClient *RPCClient) GetClusterStatus(blockStores *[]*BlockStoreStatus) error {
+	return surfClient.metaCall(func(c MetaStoreClient, ctx context.Context) error {
+		s, err := c.GetClusterStatus(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		*blockStores = s.BlockStores
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) metaCall(call func(c MetaStoreClient, ctx context.Context) error) error {
+	return surfClient.metaCallTimeout(time.Second, call)
+}
+
+// metaCallTimeout runs call against the MetaStore leader. It starts with the last server that answered,
+// and moves on to the next address while a server is down or is not the leader.
+func (surfClient *RPCClient) metaCallTimeout(timeout time.Duration, call func(c MetaStoreClient, ctx context.Context) error) error {
+	return surfClient.metaCallRetrying(timeout, notLeaderOrDown, call)
+}
+
+// notLeaderOrDown lets a call that may safely run twice go on to the next server
+func notLeaderOrDown(err error) bool {
+	switch status.Code(err) {
+	case codes.FailedPrecondition, codes.Unavailable, codes.DeadlineExceeded:
+		return true
 	}
-	// conn: to the meta store server
-	c := NewMetaStoreClient(conn)
-	// perform the call
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	blockStoreMaptemp, err := c.GetBlockStoreMap(ctx, &BlockHashes{Hashes: blockHashesIn})
-	if err != nil {
-		conn.Close()
-		return err
+	return false
+}
+
+// notLeader only lets a call go on to the next server when this one surely did not run it
+func notLeader(err error) bool {
+	return status.Code(err) == codes.FailedPrecondition
+}
+
+// metaCallRetrying is metaCallTimeout moving on to the next address for the errors retry allows
+func (surfClient *RPCClient) metaCallRetrying(timeout time.Duration, retry func(err error) bool, call func(c MetaStoreClient, ctx context.Context) error) error {
+	if surfClient.metaLeader == nil {
+		surfClient.metaLeader = new(atomic.Int64)
 	}
-	for k, v := range blockStoreMaptemp.BlockStoreMap {
-		(*blockStoreMap)[k] = v.Hashes
+	var err error
+	for round := 0; round < META_RETRY_ROUNDS; round++ {
+		if round > 0 { // maybe an election is going on, give it time
+			time.Sleep(RAFT_ELECTION_TIMEOUT)
+		}
+		start := int(surfClient.metaLeader.Load())
+		for i := range surfClient.MetaStoreAddrs {
+			index := (start + i) % len(surfClient.MetaStoreAddrs)
+			if err = surfClient.metaCallOne(surfClient.MetaStoreAddrs[index], timeout, call); err == nil {
+				surfClient.metaLeader.Store(int64(index))
+				return nil
+			}
+			if !retry(err) {
+				return err
+			}
+		}
 	}
-	// close the connection
-	return conn.Close()
+	return err
 }
 
-func (surfClient *RPCClient) GetBlockStoreAddrs(blockStoreAddrs *[]string) error {
-	//panic("todo")
+func (surfClient *RPCClient) metaCallOne(metaStoreAddr string, timeout time.Duration, call func(c MetaStoreClient, ctx context.Context) error) error {
 	// connect to the server
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(metaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return err
 	}
 	// conn: to the meta store server
 	c := NewMetaStoreClient(conn)
 	// perform the call
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	m, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
-	if err != nil {
+	if err := call(c, ctx); err != nil {
 		conn.Close()
 		return err
 	}
-	*blockStoreAddrs = m.BlockStoreAddrs
 	// close the connection
 	return conn.Close()
 }
@@ -231,29 +597,26 @@ func (surfClient *RPCClient) GetBlockStoreAddrs(blockStoreAddrs *[]string) error
 var _ ClientInterface = new(RPCClient)
 
 // Create an Surfstore RPC client
+// hostPort is the MetaStore address, or the addresses of a raft MetaStore cluster separated by CONFIG_DELIMITER
 func NewSurfstoreRPCClient(hostPort, baseDir string, blockSize int) RPCClient {
-	path := ConcatPath(baseDir, DEFAULT_META_FILENAME)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		indexFile, err := os.Create(path)
-		if err != nil {
-			log.Fatal("Error During creating file: ", err)
-		}
-		indexFile.Close()
-		db, err := sql.Open("sqlite3", path)
-		defer db.Close()
-		if err != nil {
-			log.Fatal("Error during opening index.db file", err)
-		}
-		statement, err := db.Prepare(createTable)
-		if err != nil {
-			log.Fatal("cannot create table", err)
-		}
-		statement.Exec()
-		statement.Close()
+	// create index.db, or bring it to the latest schema version
+	db, err := openMetaFile(ConcatPath(baseDir, DEFAULT_META_FILENAME))
+	if err != nil {
+		log.Fatal("Error during opening index.db file: ", err)
+	}
+	db.Close()
+	return RPCClient{
+		MetaStoreAddrs: strings.Split(hostPort, CONFIG_DELIMITER),
+		BaseDir:        baseDir,
+		BlockSize:      blockSize,
+		metaLeader:     new(atomic.Int64),
 	}
+}
+
+// Create an RPC client for administration, it talks to the servers but has no base directory to sync
+func NewAdminRPCClient(hostPort string) RPCClient {
 	return RPCClient{
-		MetaStoreAddr: hostPort,
-		BaseDir:       baseDir,
-		BlockSize:     blockSize,
+		MetaStoreAddrs: strings.Split(hostPort, CONFIG_DELIMITER),
+		metaLeader:     new(atomic.Int64),
 	}
 }
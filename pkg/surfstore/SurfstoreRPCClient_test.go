@@ -0,0 +1,85 @@
+package surfstore
+
+import (
+	context "context"
+	"sync/atomic"
+	"testing"
+
+	grpc "google.golang.org/grpc"
+)
+
+// flakyMetaStore commits the first updates it gets but answers them with an error, like a leader
+// whose answer is lost
+type flakyMetaStore struct {
+	*MetaStore
+	lost    atomic.Int32
+	updates atomic.Int32
+	err     error
+	// applies the update before failing, or fails without applying it
+	apply bool
+}
+
+func (f *flakyMetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
+	f.updates.Add(1)
+	if f.lost.Add(-1) >= 0 {
+		if f.apply {
+			f.MetaStore.UpdateFile(ctx, fileMetaData)
+		}
+		return nil, f.err
+	}
+	return f.MetaStore.UpdateFile(ctx, fileMetaData)
+}
+
+func startFlakyMetaStore(t *testing.T, flaky *flakyMetaStore) string {
+	t.Helper()
+	server := grpc.NewServer()
+	RegisterMetaStoreServer(server, flaky)
+	return serveTest(t, server)
+}
+
+func TestUpdateFileCommittedButUnansweredIsNotAConflict(t *testing.T) {
+	flaky := &flakyMetaStore{MetaStore: NewMetaStore([]string{}), err: ERR_LEADERSHIP_LOST, apply: true}
+	flaky.lost.Store(1)
+	client := newTestClient(t, startFlakyMetaStore(t, flaky), 1024)
+
+	version := int32(0)
+	fileMetaData := &FileMetaData{Filename: "a.txt", Version: 1, BlockHashList: []string{"h"}}
+	if err := client.UpdateFile(fileMetaData, &version); err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Fatalf("an update committed by an attempt without an answer came back as version %d", version)
+	}
+	if n := flaky.updates.Load(); n != 1 {
+		t.Fatalf("the committed update was sent %d times", n)
+	}
+}
+
+func TestUpdateFileLostBeforeCommitIsSentAgain(t *testing.T) {
+	flaky := &flakyMetaStore{MetaStore: NewMetaStore([]string{}), err: ERR_LEADERSHIP_LOST}
+	flaky.lost.Store(1)
+	client := newTestClient(t, startFlakyMetaStore(t, flaky), 1024)
+
+	version := int32(0)
+	if err := client.UpdateFile(&FileMetaData{Filename: "a.txt", Version: 1, BlockHashList: []string{"h"}}, &version); err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || flaky.updates.Load() != 2 {
+		t.Fatalf("got version %d after %d updates, want version 1 after 2", version, flaky.updates.Load())
+	}
+}
+
+func TestUpdateFileMovesOnFromAFollower(t *testing.T) {
+	follower := &flakyMetaStore{MetaStore: NewMetaStore([]string{}), err: ERR_NOT_LEADER}
+	follower.lost.Store(1 << 20)
+	leader := &flakyMetaStore{MetaStore: NewMetaStore([]string{})}
+	client := newTestClient(t, startFlakyMetaStore(t, follower)+CONFIG_DELIMITER+startFlakyMetaStore(t, leader), 1024)
+
+	version := int32(0)
+	if err := client.UpdateFile(&FileMetaData{Filename: "a.txt", Version: 1, BlockHashList: []string{"h"}}, &version); err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || leader.updates.Load() != 1 {
+		t.Fatalf("got version %d after %d updates on the leader, want version 1 after 1", version, leader.updates.Load())
+	}
+}
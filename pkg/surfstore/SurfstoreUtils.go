@@ -1,17 +1,48 @@
 package surfstore
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func ClientSync(client RPCClient) {
-	baseDir, localFileInfoMap, err := getLocalInfo(client)             // get localIndex
-	err = updateLocalIndexFile(client, err, baseDir, localFileInfoMap) // update localIndex (new, delete, change)
+	baseDir, localFileInfoMap, err := getLocalInfo(client) // get localIndex
+	journal, err := OpenSyncJournal(baseDir)
+	if err != nil {
+		log.Fatalf("Error while opening the sync journal: %v", err)
+	}
+	defer journal.Close()
+	if err := journal.Replay(client, localFileInfoMap); err != nil { // finish the sync that was interrupted, if any
+		log.Fatalf("Error while replaying the sync journal: %v", err)
+	}
+	fileStats, err := LoadFileStats(baseDir)
+	if err != nil {
+		log.Fatalf("Error while loading file stats from index.db: %v", err)
+	}
+	err = updateLocalIndexFile(client, err, baseDir, localFileInfoMap, fileStats) // update localIndex (new, delete, change)
 	log.Println("Local index updated")
+	scanned := make(map[string]*FileMetaData, len(localFileInfoMap))
+	for filename, fileMetaData := range localFileInfoMap {
+		scanned[filename] = fileMetaData
+	}
 	remoteIndex, err := getRemoteIndexFile(client, err)
 	log.Println("Remote index updated")
+	uploaded := syncedBlocks{}        // blocks already on the block servers, shared by every upload of this run
+	blockStores := &BlockStoreAddrs{} // the ring and which files to erasure code
+	if err := client.GetBlockStoreRing(blockStores); err != nil {
+		log.Fatalf("Error while getting the block store ring from the server: %v", err)
+	}
 	for remoteFilename, remoteFileMetaData := range remoteIndex {
 		log.Println(">>>>>>>>>>>Syncing file: ", remoteFilename)
 		log.Println("Remote file version: ", remoteFileMetaData.Version)
@@ -19,7 +50,10 @@ func ClientSync(client RPCClient) {
 
 			if remoteFileMetaData.BlockHashList[0] != "0" { // remote file is not deleted, download file
 				log.Println("Downloading file: ", remoteFilename)
-				downloadFile(client, remoteFileMetaData, err, remoteFilename, localFileInfoMap)
+				journaled(journal, false, remoteFileMetaData, func() bool {
+					downloadFile(client, remoteFileMetaData, blockStores, err, remoteFilename, localFileInfoMap)
+					return true
+				})
 			} else { // remote file is deleted, update local index
 				log.Println("Deleting file: ", remoteFilename)
 				localFileInfoMap[remoteFilename] = remoteFileMetaData
@@ -30,27 +64,35 @@ func ClientSync(client RPCClient) {
 			if localFileMetaData.Version > remoteFileMetaData.Version {
 				if localFileMetaData.BlockHashList[0] == "0" { // - local hash[0] == "0" -> delete remote file
 					log.Println("Deleting remote file: ", remoteFilename)
-					returnedVersion, _ := updateRemoteFile(client, remoteFilename, localFileMetaData.Version, []string{"0"})
+					var returnedVersion int32
+					journaled(journal, true, localFileMetaData, func() bool {
+						returnedVersion, _ = updateRemoteFile(client, remoteFilename, localFileMetaData.Version, []string{"0"}, nil)
+						return returnedVersion != -1
+					})
 					if returnedVersion == -1 { // conflict
 						log.Println("Conflict: ", remoteFilename)
-						coflictReturnHandle(client, remoteIndex, err, remoteFileMetaData, remoteFilename, baseDir, localFileInfoMap)
+						coflictReturnHandle(client, remoteIndex, err, remoteFileMetaData, remoteFilename, baseDir, localFileInfoMap, blockStores, journal)
 					}
 				} else { // upload file
 					log.Println("Uploading file: ", remoteFilename)
-					returnedVersion, err := uploadFile(client, remoteFilename, localFileMetaData)
+					var returnedVersion int32
+					journaled(journal, true, localFileMetaData, func() bool {
+						returnedVersion, err = uploadFile(client, remoteFilename, localFileMetaData, blockStores, uploaded)
+						return returnedVersion != -1
+					})
 					if returnedVersion == -1 { // conflict
 						log.Println("Conflict: ", remoteFilename)
-						coflictReturnHandle(client, remoteIndex, err, remoteFileMetaData, remoteFilename, baseDir, localFileInfoMap)
+						coflictReturnHandle(client, remoteIndex, err, remoteFileMetaData, remoteFilename, baseDir, localFileInfoMap, blockStores, journal)
 					}
 				}
 
 			} else if localFileMetaData.Version < remoteFileMetaData.Version {
 				log.Println("Syncing with remote: ", remoteFilename)
-				syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, err)
+				syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, blockStores, err, journal)
 			} else if localFileMetaData.Version == remoteFileMetaData.Version {
 				if !CompareBlockHashList(localFileMetaData.BlockHashList, remoteFileMetaData.BlockHashList) {
 					log.Println("conflict, syncing with remote: ", remoteFilename)
-					syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, err)
+					syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, blockStores, err, journal)
 				}
 			}
 		}
@@ -61,92 +103,305 @@ func ClientSync(client RPCClient) {
 		if _, ok := remoteIndex[localFilename]; !ok {
 			if localFileMetaData.BlockHashList[0] != "0" { // local file is not deleted, upload file
 				log.Println("Uploading file: ", localFilename)
-				returnedVersion, err := uploadFile(client, localFilename, localFileMetaData)
+				var returnedVersion int32
+				journaled(journal, true, localFileMetaData, func() bool {
+					returnedVersion, err = uploadFile(client, localFilename, localFileMetaData, blockStores, uploaded)
+					return returnedVersion != -1
+				})
 				if returnedVersion == -1 { // conflict
 					log.Println("Conflict: ", localFilename)
-					coflictReturnHandle(client, remoteIndex, err, localFileMetaData, localFilename, baseDir, localFileInfoMap)
+					coflictReturnHandle(client, remoteIndex, err, localFileMetaData, localFilename, baseDir, localFileInfoMap, blockStores, journal)
 				}
 			}
 		}
 	}
-	err = WriteMetaFile(localFileInfoMap, baseDir)
+	// a file downloaded or deleted since the scan is not the file its stat data is of
+	for filename := range fileStats {
+		if localFileInfoMap[filename] != scanned[filename] {
+			delete(fileStats, filename)
+		}
+	}
+	if err := WriteMetaFileWithStats(localFileInfoMap, fileStats, baseDir); err != nil {
+		log.Fatalf("Error while writing index.db: %v", err)
+	}
+	if err := journal.Reset(); err != nil { // index.db has every operation now
+		log.Fatalf("Error while resetting the sync journal: %v", err)
+	}
 	log.Println("Local index updated, done")
 }
 
-func coflictReturnHandle(client RPCClient, remoteIndex map[string]*FileMetaData, err error, remoteFileMetaData *FileMetaData, remoteFilename string, baseDir string, localFileInfoMap map[string]*FileMetaData) {
+// journaled runs op between its intent and its done record in the journal. op leaves the file
+// as fileMetaData if it returns true, remote tells whether it does so on the MetaStore
+func journaled(journal *SyncJournal, remote bool, fileMetaData *FileMetaData, op func() bool) {
+	id, err := journal.Begin(remote, fileMetaData)
+	if err != nil {
+		log.Fatalf("Error while writing the sync journal: %v", err)
+	}
+	if !op() {
+		return
+	}
+	if err := journal.Done(id); err != nil {
+		log.Fatalf("Error while writing the sync journal: %v", err)
+	}
+}
+
+func coflictReturnHandle(client RPCClient, remoteIndex map[string]*FileMetaData, err error, remoteFileMetaData *FileMetaData, remoteFilename string, baseDir string, localFileInfoMap map[string]*FileMetaData, blockStores *BlockStoreAddrs, journal *SyncJournal) {
 	remoteIndex, _ = getRemoteIndexFile(client, err) // get new remote index
 	remoteFileMetaData = remoteIndex[remoteFilename]
-	syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, err)
+	syncWithRemote(client, remoteFileMetaData, baseDir, remoteFilename, localFileInfoMap, blockStores, err, journal)
 }
 
-func syncWithRemote(client RPCClient, remoteFileMetaData *FileMetaData, baseDir string, remoteFilename string, localFileInfoMap map[string]*FileMetaData, err error) {
+func syncWithRemote(client RPCClient, remoteFileMetaData *FileMetaData, baseDir string, remoteFilename string, localFileInfoMap map[string]*FileMetaData, blockStores *BlockStoreAddrs, err error, journal *SyncJournal) {
 	if remoteFileMetaData.BlockHashList[0] == "0" { // delete local file
 		log.Println("Deleting local file: ", remoteFilename)
-		os.Remove(ConcatPath(baseDir, remoteFilename))
-		localFileInfoMap[remoteFilename] = remoteFileMetaData
+		journaled(journal, false, remoteFileMetaData, func() bool {
+			os.Remove(ConcatPath(baseDir, remoteFilename))
+			localFileInfoMap[remoteFilename] = remoteFileMetaData
+			return true
+		})
 	} else { // download file
 		log.Println("Downloading file: ", remoteFilename)
-		downloadFile(client, remoteFileMetaData, err, remoteFilename, localFileInfoMap)
+		journaled(journal, false, remoteFileMetaData, func() bool {
+			downloadFile(client, remoteFileMetaData, blockStores, err, remoteFilename, localFileInfoMap)
+			return true
+		})
 	}
 }
 
-func uploadFile(client RPCClient, remoteFilename string, localFileMetaData *FileMetaData) (returnedVersion int32, err error) {
-	blockStoreMap := map[string][]string{}
-	err = client.GetBlockStoreMap(localFileMetaData.BlockHashList, &blockStoreMap)
-	if err != nil {
-		log.Fatalf("Error while getting block store map from the server: %v", err)
+// syncedBlocks remembers, for one sync run, which blocks each block server is known to hold:
+// server address -> block hash -> true
+type syncedBlocks map[string]map[string]bool
+
+// forEachServer runs put for every server, at most client.Jobs at a time. Every server has its
+// entry in uploaded before any put runs, so a put only ever touches the entry of its own server
+func forEachServer(client RPCClient, servers []string, uploaded syncedBlocks, put func(serverAddr string)) {
+	for _, serverAddr := range servers {
+		if uploaded[serverAddr] == nil {
+			uploaded[serverAddr] = map[string]bool{}
+		}
 	}
-	// change map to block hash -> server address
-	hashToServer := map[string]string{}
-	for serverAddr, blockHashes := range blockStoreMap {
-		for _, blockHash := range blockHashes {
-			hashToServer[blockHash] = serverAddr
+	runJobs(client.Jobs, len(servers), func(i int) {
+		put(servers[i])
+	})
+}
+
+// runJobs calls job for 0 to n-1, at most jobs calls at a time, and returns once every call has returned
+func runJobs(jobs int, n int, job func(i int)) {
+	if jobs <= 1 {
+		for i := 0; i < n; i++ {
+			job(i)
 		}
+		return
 	}
-	localPath := filepath.Join(client.BaseDir, remoteFilename)
-	file, err := os.Open(localPath)
-	if err != nil {
-		log.Fatalf("Cannot open file %s: %v", localPath, err)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				job(i)
+			}
+		}()
 	}
-	defer file.Close()
-	filestats, err := file.Stat()
-	if err != nil {
-		log.Fatalf("Cannot get file stats %s: %v", localPath, err)
+	for i := 0; i < n; i++ {
+		next <- i
 	}
-	blocknum := filestats.Size() / int64(client.BlockSize)
-	if filestats.Size()%int64(client.BlockSize) != 0 {
-		blocknum++
+	close(next)
+	wg.Wait()
+}
+
+// uploadFile puts the blocks of a file on the block servers, erasure coded if blockStores says so,
+// then updates the file on the MetaStore
+func uploadFile(client RPCClient, remoteFilename string, localFileMetaData *FileMetaData, blockStores *BlockStoreAddrs, uploaded syncedBlocks) (returnedVersion int32, err error) {
+	var stripes []*Stripe
+	if localFileMetaData.BlockHashList[0] != EMPTYFILE_HASHVALUE { // empty file has no block to upload
+		localPath := filepath.Join(client.BaseDir, remoteFilename)
+		file, err := os.Open(localPath)
+		if err != nil {
+			log.Fatalf("Cannot open file %s: %v", localPath, err)
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			log.Fatalf("Cannot stat file %s: %v", localPath, err)
+		}
+		if blockStores.ErasureCoding.Applies(remoteFilename, info.Size()) {
+			stripes = uploadStripes(client, file, localFileMetaData.BlockHashList, blockStores, uploaded)
+		} else {
+			uploadBlocks(client, file, localFileMetaData.BlockHashList, blockStores, uploaded)
+		}
 	}
+	returnedVersion, err = updateRemoteFile(client, remoteFilename, localFileMetaData.Version, localFileMetaData.BlockHashList, stripes)
+	return returnedVersion, err
+}
+
+// uploadBlocks puts the blocks of a file on every one of their replicas, on client.Jobs block servers at once.
+// A block server that fails does not stop the upload as long as every block reaches a write quorum,
+// a majority of the replicas the ring keeps of it. The replicas that missed a block get it from
+// anti-entropy or read repair later
+func uploadBlocks(client RPCClient, file *os.File, blockHashList []string, blockStores *BlockStoreAddrs, uploaded syncedBlocks) {
+	blockStoreMap := getBlockStoreMap(client, blockHashList)
 
-	if blocknum > 0 {
-		blockData := make([]byte, client.BlockSize)
-		for i := int64(0); i < blocknum; i++ {
-			n, err := file.Read(blockData)
+	// change list to block hash -> block index in the file
+	hashToIndex := map[string]int64{}
+	for i, blockHash := range blockHashList {
+		if _, ok := hashToIndex[blockHash]; !ok {
+			hashToIndex[blockHash] = int64(i)
+		}
+	}
+	servers := make([]string, 0, len(blockStoreMap))
+	for serverAddr := range blockStoreMap {
+		servers = append(servers, serverAddr)
+	}
+	sort.Strings(servers)
+	var failedMu sync.Mutex
+	failed := map[string]error{}
+	forEachServer(client, servers, uploaded, func(serverAddr string) {
+		blockHashes, err := missingBlocks(client, serverAddr, blockStoreMap[serverAddr], uploaded)
+		if err != nil {
+			failedMu.Lock()
+			failed[serverAddr] = err
+			failedMu.Unlock()
+			return
+		}
+		if len(blockHashes) == 0 {
+			return
+		}
+		// one stream per block server, each block is read from the file right before it is sent.
+		// A block is listed under every server holding a replica of it, so each replica gets a copy
+		next := 0
+		var success bool
+		err = client.PutBlocks(serverAddr, func() (*Block, error) {
+			if next == len(blockHashes) {
+				return nil, io.EOF
+			}
+			next++
+			block, err := readBlock(file, hashToIndex[blockHashes[next-1]], client.BlockSize)
 			if err != nil {
-				log.Fatalf("Cannot read block %d from file %s: %v", i, localPath, err)
+				return nil, err
 			}
-			var block Block
-			block.BlockData = blockData[:n]
-			block.BlockSize = int32(n)
-
-			// get block store address
-			blockStoreAddr := hashToServer[GetBlockHashString(block.BlockData)]
-			var success bool
-			err = client.PutBlock(&block, blockStoreAddr, &success)
-			if err != nil || !success {
-				log.Fatalf("Error while putting block %d to the server: %v", i, err)
+			// the block store checks it is still the block we hashed
+			block.Hash = blockHashes[next-1]
+			return block, nil
+		}, &success)
+		if err == nil && !success {
+			err = errors.New("the server did not store the blocks")
+		}
+		if err != nil {
+			failedMu.Lock()
+			failed[serverAddr] = err
+			failedMu.Unlock()
+			return
+		}
+		for _, blockHash := range blockHashes {
+			uploaded[serverAddr][blockHash] = true
+		}
+	})
+	for serverAddr, err := range failed {
+		log.Printf("Error while putting blocks of %s to the server %s: %v", file.Name(), serverAddr, err)
+	}
+	if err := checkWriteQuorum(blockReplicas(blockStoreMap, blockStores), failed, writeQuorum(blockStores)); err != nil {
+		log.Fatalf("Error while uploading %s: %v", file.Name(), err)
+	}
+}
+
+// writeQuorum is a majority of the replicas the ring keeps of a block. It is counted over the
+// replication factor and not over the replicas the MetaStore lists, which leaves out the ones it
+// takes for down
+func writeQuorum(blockStores *BlockStoreAddrs) int {
+	replication := min(max(int(blockStores.Replication), 1), len(blockStores.BlockStoreAddrs))
+	return replication/2 + 1
+}
+
+// checkWriteQuorum fails unless every block reached quorum of its replicas, the ones in failed did not
+// get it
+func checkWriteQuorum(replicas map[string][]string, failed map[string]error, quorum int) error {
+	for blockHash, servers := range replicas {
+		stored := 0
+		for _, serverAddr := range servers {
+			if _, ok := failed[serverAddr]; !ok {
+				stored++
 			}
 		}
+		if stored < quorum {
+			return fmt.Errorf("block %s reached %d of its %d live replicas, it needs %d", blockHash, stored, len(servers), quorum)
+		}
+		if stored < len(servers) {
+			log.Printf("Block %s reached %d of its %d replicas, the others are repaired later", blockHash, stored, len(servers))
+		}
 	}
-	returnedVersion, err = updateRemoteFile(client, remoteFilename, localFileMetaData.Version, localFileMetaData.BlockHashList)
-	return returnedVersion, err
+	return nil
+}
+
+// missingBlocks returns the distinct hashes of blockHashes the block server does not hold yet,
+// skipping the ones this run already knows about and remembering the ones the server already has
+func missingBlocks(client RPCClient, serverAddr string, blockHashes []string, uploaded syncedBlocks) ([]string, error) {
+	if uploaded[serverAddr] == nil {
+		uploaded[serverAddr] = map[string]bool{}
+	}
+	unknown := make([]string, 0)
+	seen := map[string]bool{}
+	for _, blockHash := range blockHashes {
+		if !uploaded[serverAddr][blockHash] && !seen[blockHash] {
+			seen[blockHash] = true
+			unknown = append(unknown, blockHash)
+		}
+	}
+	missing := make([]string, 0)
+	for start := 0; start < len(unknown); start += HASH_BATCH_SIZE {
+		batch := unknown[start:min(start+HASH_BATCH_SIZE, len(unknown))]
+		batchMissing := []string{}
+		if err := client.MissingBlocks(batch, serverAddr, &batchMissing); err != nil {
+			return nil, fmt.Errorf("cannot get missing blocks: %w", err)
+		}
+		missing = append(missing, batchMissing...)
+	}
+	isMissing := map[string]bool{}
+	for _, blockHash := range missing {
+		isMissing[blockHash] = true
+	}
+	for _, blockHash := range unknown {
+		if !isMissing[blockHash] {
+			uploaded[serverAddr][blockHash] = true
+		}
+	}
+	return missing, nil
+}
+
+// getBlockStoreMap asks the MetaStore where the blocks live, HASH_BATCH_SIZE hashes at a time
+// so a large file does not go over the grpc message size limit
+func getBlockStoreMap(client RPCClient, blockHashes []string) map[string][]string {
+	blockStoreMap := map[string][]string{}
+	for start := 0; start < len(blockHashes); start += HASH_BATCH_SIZE {
+		batchMap := map[string][]string{}
+		err := client.GetBlockStoreMap(blockHashes[start:min(start+HASH_BATCH_SIZE, len(blockHashes))], &batchMap)
+		if err != nil {
+			log.Fatalf("Error while getting block store map from the server: %v", err)
+		}
+		for serverAddr, batchHashes := range batchMap {
+			blockStoreMap[serverAddr] = append(blockStoreMap[serverAddr], batchHashes...)
+		}
+	}
+	return blockStoreMap
 }
 
-func updateRemoteFile(client RPCClient, name string, version int32, blockHashList []string) (returnedVersion int32, err error) {
+// readBlock reads block number index of the file
+func readBlock(file *os.File, index int64, blockSize int) (*Block, error) {
+	blockData := make([]byte, blockSize)
+	n, err := file.ReadAt(blockData, index*int64(blockSize))
+	if err != nil && !(err == io.EOF && n > 0) { // the last block is shorter
+		return nil, err
+	}
+	return &Block{BlockData: blockData[:n], BlockSize: int32(n)}, nil
+}
+
+func updateRemoteFile(client RPCClient, name string, version int32, blockHashList []string, stripes []*Stripe) (returnedVersion int32, err error) {
 	remoteFileupdate := &FileMetaData{
 		Filename:      name,
 		Version:       version,
 		BlockHashList: blockHashList,
+		Stripes:       stripes,
 	}
 	err = client.UpdateFile(remoteFileupdate, &returnedVersion)
 	if err != nil {
@@ -155,47 +410,222 @@ func updateRemoteFile(client RPCClient, name string, version int32, blockHashLis
 	return returnedVersion, err
 }
 
-func downloadFile(client RPCClient, remoteFileMetaData *FileMetaData, err error, remoteFilename string, localFileInfoMap map[string]*FileMetaData) {
-	if len(remoteFileMetaData.BlockHashList) == 1 && remoteFileMetaData.BlockHashList[0] == "-1" { //empty file, no need to download,  only open local path and exit
-		localPath := ConcatPath(client.BaseDir, remoteFilename)
-		localFile, err := os.Create(localPath)
-		if err != nil {
-			log.Fatalf("Cannot create file %s: %v", localPath, err)
+// downloadFile downloads a file into a temp file in baseDir, fsyncs it, checks it against its block
+// hashes and only then renames it over the local file. A download that fails or a client that
+// crashes leaves the local file as it was, and at most a temp file the next sync removes
+func downloadFile(client RPCClient, remoteFileMetaData *FileMetaData, blockStores *BlockStoreAddrs, err error, remoteFilename string, localFileInfoMap map[string]*FileMetaData) {
+	localPath := ConcatPath(client.BaseDir, remoteFilename)
+	tmp, err := os.CreateTemp(client.BaseDir, downloadFilePrefix+remoteFilename+"-")
+	if err != nil {
+		log.Fatalf("Cannot create a temp file for %s: %v", localPath, err)
+	}
+	// CreateTemp makes the file 0600, give it the usual permissions
+	err = tmp.Chmod(0644)
+
+	// the length of every block written, to check them again once they are on disk
+	blockSizes := make([]int, 0, len(remoteFileMetaData.BlockHashList))
+	write := func(block *Block) error {
+		blockSizes = append(blockSizes, len(block.BlockData))
+		_, err := tmp.Write(block.BlockData)
+		return err
+	}
+	empty := len(remoteFileMetaData.BlockHashList) == 1 && remoteFileMetaData.BlockHashList[0] == EMPTYFILE_HASHVALUE
+	if err == nil && !empty {
+		if len(remoteFileMetaData.Stripes) > 0 {
+			err = downloadStripes(client, remoteFileMetaData, blockStores, write)
+		} else {
+			replicas := blockReplicas(getBlockStoreMap(client, remoteFileMetaData.BlockHashList), blockStores)
+			err = downloadBlocks(client, remoteFileMetaData.BlockHashList, replicas, write)
 		}
-		defer localFile.Close()
-		localFileInfoMap[remoteFilename] = remoteFileMetaData
-		return
 	}
-	blockStoreMap := map[string][]string{}
-	err = client.GetBlockStoreMap(remoteFileMetaData.BlockHashList, &blockStoreMap)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil && !empty {
+		err = verifyFile(tmp, remoteFileMetaData.BlockHashList, blockSizes)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), localPath)
+	}
 	if err != nil {
-		log.Fatalf("Error while getting block store map from the server: %v", err)
+		os.Remove(tmp.Name())
+		log.Fatalf("Error while downloading file %s: %v", localPath, err)
+	}
+	if err := syncDir(client.BaseDir); err != nil {
+		log.Fatalf("Error while downloading file %s: %v", localPath, err)
+	}
+	localFileInfoMap[remoteFilename] = remoteFileMetaData
+}
+
+// verifyFile reads a downloaded file back and checks it is made of the blocks of blockHashes,
+// blockSizes long, and nothing else
+func verifyFile(file *os.File, blockHashes []string, blockSizes []int) error {
+	if len(blockSizes) != len(blockHashes) {
+		return fmt.Errorf("wrote %d blocks, the file has %d", len(blockSizes), len(blockHashes))
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
 	}
-	hashToServer := map[string]string{} // change map to block hash -> server address
+	for i, blockHash := range blockHashes {
+		blockData := make([]byte, blockSizes[i])
+		if _, err := io.ReadFull(file, blockData); err != nil {
+			return fmt.Errorf("cannot read block %d back: %w", i, err)
+		}
+		if GetBlockHashString(blockData) != blockHash {
+			return fmt.Errorf("block %d does not match %s once written", i, blockHash)
+		}
+	}
+	if n, _ := file.Read(make([]byte, 1)); n > 0 {
+		return errors.New("file is longer than its blocks")
+	}
+	return nil
+}
+
+// blockReplicas turns a block store map into block hash -> the servers holding it, in the order
+// to read from them: a download reads every block from the first of its replicas that answers,
+// and only moves on to the next one when that fails. The map has lost the order the MetaStore
+// placed them in, so it is the order of the ring again, the replicas of a block first
+func blockReplicas(blockStoreMap map[string][]string, blockStores *BlockStoreAddrs) map[string][]string {
+	replicas := map[string][]string{}
 	for serverAddr, blockHashes := range blockStoreMap {
 		for _, blockHash := range blockHashes {
-			hashToServer[blockHash] = serverAddr
+			if !containsString(replicas[blockHash], serverAddr) {
+				replicas[blockHash] = append(replicas[blockHash], serverAddr)
+			}
 		}
 	}
-	localPath := ConcatPath(client.BaseDir, remoteFilename)
-	localFile, err := os.Create(localPath)
-	if err != nil {
-		log.Fatalf("Cannot create file %s: %v", localPath, err)
+	ring := newBlockStoreRing(blockStores)
+	replication := max(int(blockStores.Replication), 1)
+	for blockHash, servers := range replicas {
+		rank := map[string]int{}
+		order := append(ring.responsibleServers(blockHash, replication), ring.responsibleServers(blockHash, len(blockStores.BlockStoreAddrs))...)
+		for i, serverAddr := range order {
+			if _, ok := rank[serverAddr]; !ok {
+				rank[serverAddr] = i
+			}
+		}
+		rankOf := func(serverAddr string) int {
+			if i, ok := rank[serverAddr]; ok {
+				return i
+			}
+			return len(order)
+		}
+		sort.SliceStable(servers, func(i, j int) bool { return rankOf(servers[i]) < rankOf(servers[j]) })
 	}
-	defer localFile.Close()
-	for _, blockHash := range remoteFileMetaData.BlockHashList {
-		var block Block // get block store address
-		blockStoreAddr := hashToServer[blockHash]
-		err = client.GetBlock(blockHash, blockStoreAddr, &block)
-		if err != nil {
-			log.Fatalf("Error while getting block %s from the server: %v", blockHash, err)
+	return replicas
+}
+
+// downloadBlocks hands the blocks of blockHashes to write, in order. There is one stream per block server,
+// asking for its blocks in file order. When a server fails, the download starts over from the block
+// it failed on, reading the remaining blocks from the next replica of each. A server that does not have
+// a block, or sends it with data that does not match its hash, is only skipped for that block.
+func downloadBlocks(client RPCClient, blockHashes []string, replicas map[string][]string, write func(*Block) error) error {
+	failed := map[string]bool{}
+	// hash -> replicas that answered they do not have the block, they stay in use for the others
+	missing := map[string][]string{}
+	// hash -> replicas that sent the block corrupted, same
+	corrupt := map[string][]string{}
+	next := 0
+	for next < len(blockHashes) {
+		// the first replica of each remaining block that has not failed
+		hashToServer := map[string]string{}
+		serverHashes := map[string][]string{}
+		for _, blockHash := range blockHashes[next:] {
+			if _, ok := hashToServer[blockHash]; !ok {
+				for _, serverAddr := range replicas[blockHash] {
+					if !failed[serverAddr] && !containsString(missing[blockHash], serverAddr) && !containsString(corrupt[blockHash], serverAddr) {
+						hashToServer[blockHash] = serverAddr
+						break
+					}
+				}
+				if _, ok := hashToServer[blockHash]; !ok {
+					return fmt.Errorf("no block server left with a good copy of block %s", blockHash)
+				}
+			}
+			serverAddr := hashToServer[blockHash]
+			serverHashes[serverAddr] = append(serverHashes[serverAddr], blockHash)
 		}
-		_, err = localFile.Write(block.BlockData) // sync write block to file
-		if err != nil {
-			log.Fatalf("Error while writing block to file %s: %v", localPath, err)
+		done := make(chan struct{})
+		streams := map[string]*blockStream{}
+		for serverAddr, serverBlockHashes := range serverHashes {
+			streams[serverAddr] = startBlockStream(client, serverAddr, serverBlockHashes, done)
 		}
+		// take the blocks from the streams in file order
+		for ; next < len(blockHashes); next++ {
+			serverAddr := hashToServer[blockHashes[next]]
+			block, ok := <-streams[serverAddr].blocks
+			if !ok {
+				err := streams[serverAddr].err
+				log.Printf("Error while getting block %s from the server %s: %v", blockHashes[next], serverAddr, err)
+				if status.Code(err) == codes.NotFound {
+					// the stream stops at the first block the server lacks, which is this one
+					missing[blockHashes[next]] = append(missing[blockHashes[next]], serverAddr)
+				} else {
+					failed[serverAddr] = true
+				}
+				break
+			}
+			// nothing reaches the file before it is checked against its hash
+			if hash := GetBlockHashString(block.BlockData); hash != blockHashes[next] {
+				log.Printf("The server %s sent block %s hashing to %s, trying another replica", serverAddr, blockHashes[next], hash)
+				corrupt[blockHashes[next]] = append(corrupt[blockHashes[next]], serverAddr)
+				break
+			}
+			if lacking, ok := missing[blockHashes[next]]; ok {
+				readRepair(client, blockHashes[next], block, lacking)
+				delete(missing, blockHashes[next])
+			}
+			if err := write(block); err != nil {
+				close(done)
+				return err
+			}
+		}
+		close(done)
 	}
-	localFileInfoMap[remoteFilename] = remoteFileMetaData
+	return nil
+}
+
+// readRepair writes a block back to the replicas that did not have it. A failed write only gets
+// logged, the download goes on and anti-entropy repairs the replica later
+func readRepair(client RPCClient, hash string, block *Block, replicas []string) {
+	for _, serverAddr := range replicas {
+		var succ bool
+		if err := client.PutBlock(&Block{BlockData: block.BlockData, BlockSize: block.BlockSize, Hash: hash}, serverAddr, &succ); err != nil {
+			log.Printf("Error while writing block %s back to the server %s: %v", hash, serverAddr, err)
+			continue
+		}
+		log.Printf("Wrote block %s back to the server %s", hash, serverAddr)
+	}
+}
+
+// blockStream hands over the blocks of a GetBlocks stream. At most BLOCK_STREAM_BUFFER blocks wait
+// in blocks, after that the stream stops reading and grpc flow control holds back the server.
+// err is set before blocks is closed.
+type blockStream struct {
+	blocks chan *Block
+	err    error
+}
+
+func startBlockStream(client RPCClient, serverAddr string, blockHashes []string, done <-chan struct{}) *blockStream {
+	stream := &blockStream{blocks: make(chan *Block, BLOCK_STREAM_BUFFER)}
+	go func() {
+		defer close(stream.blocks)
+		stream.err = client.GetBlocks(blockHashes, serverAddr, func(block *Block) error {
+			select {
+			case stream.blocks <- block:
+				return nil
+			case <-done:
+				return errors.New("download stopped")
+			}
+		})
+		if stream.err == nil {
+			stream.err = errors.New("stream ended early")
+		}
+	}()
+	return stream
 }
 
 func getRemoteIndexFile(client RPCClient, err error) (map[string]*FileMetaData, error) {
@@ -234,47 +664,99 @@ func getLocalInfo(client RPCClient) (string, map[string]*FileMetaData, error) {
 	return baseDir, localFileInfoMap, err
 }
 
-func blockToHash(path string, blocknum int64, client RPCClient, file *os.File, blockHashList []string) (error, bool) {
-	for i := int64(0); i < blocknum; i++ {
-		block := make([]byte, client.BlockSize)
-		n, err := file.Read(block)
-		if err != nil {
-			log.Printf("Cannot read block %d from file %s: %v", i, path, err)
-			return nil, true
+// hashLocalFile returns the block hash list of the file at path, EMPTYFILE_HASHVALUE for an empty file
+func hashLocalFile(path string, blockSize int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	blockHashList := make([]string, 0)
+	block := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(file, block)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
 		}
-		blockHashList[i] = GetBlockHashString(block[:n])
+		blockHashList = append(blockHashList, GetBlockHashString(block[:n]))
+		if n < blockSize {
+			break
+		}
+	}
+	if len(blockHashList) == 0 {
+		blockHashList = append(blockHashList, EMPTYFILE_HASHVALUE)
 	}
-	return nil, false
+	return blockHashList, nil
 }
 
-func updateLocalIndexFile(client RPCClient, err error, baseDir string, localFileInfoMap map[string]*FileMetaData) error {
+// updateLocalIndexFile brings the local index up to the files in baseDir. A file is only hashed if
+// its stat data is not the one in fileStats, or the client is paranoid, and fileStats gets the
+// stat data of every file hashed. Afterwards fileStats only has files that are in baseDir.
+// client.Jobs files are hashed at once, the index is updated in the order of the walk all the same
+func updateLocalIndexFile(client RPCClient, err error, baseDir string, localFileInfoMap map[string]*FileMetaData, fileStats map[string]*FileStat) error {
+	scanStart := time.Now()
+	unchanged := 0
+	present := map[string]bool{}
+	// the files to hash, in the order of the walk
+	type hashJob struct {
+		path string
+		info os.FileInfo
+		stat FileStat
+	}
+	jobs := make([]hashJob, 0)
 	err = filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && info.Name() != ".DS_Store" && info.Name() != "index.db" {
-			file, err := os.Open(path)
-			if err != nil {
-				log.Printf("Cannot open file %s: %v", path, err)
-				return nil
-			}
-			defer file.Close()
-			blocknum := info.Size() / int64(client.BlockSize)
-			if info.Size()%int64(client.BlockSize) != 0 {
-				blocknum++
-			}
-			blockHashList := make([]string, blocknum)
-			if blocknum > 0 {
-				err2, done := blockToHash(path, blocknum, client, file, blockHashList)
-				if done {
-					return err2
+		if !info.IsDir() && strings.HasPrefix(info.Name(), downloadFilePrefix) {
+			// left behind by a download that did not finish, nothing refers to it. The leftover of
+			// the first download of a file goes once a later one has put the file in the index
+			if isDownloadLeftover(info.Name(), localFileInfoMap) {
+				if err := os.Remove(path); err != nil {
+					log.Printf("Cannot remove temp file %s: %v", path, err)
 				}
-			} else {
-				blockHashList = make([]string, 1)
-				blockHashList[0] = "-1"
 			}
-			compareLocalIndexFile(localFileInfoMap, info, blockHashList, baseDir) // compare with local index file
+			return nil
+		}
+		if !info.IsDir() && info.Name() != ".DS_Store" && !isIndexFile(info.Name()) {
+			present[info.Name()] = true
+			stat := statOf(info)
+			indexed, ok := localFileInfoMap[info.Name()]
+			if old := fileStats[info.Name()]; !client.Paranoid && old != nil && *old == stat && ok && indexed.BlockHashList[0] != TOMBSTONE_HASHVALUE {
+				unchanged++ // the blocks in the index are still the blocks of the file
+				return nil
+			}
+			delete(fileStats, info.Name())
+			jobs = append(jobs, hashJob{path: path, info: info, stat: stat})
 		}
 		return nil
 	})
+
+	blockHashLists := make([][]string, len(jobs))
+	hashErrs := make([]error, len(jobs))
+	runJobs(client.Jobs, len(jobs), func(i int) {
+		blockHashLists[i], hashErrs[i] = hashLocalFile(jobs[i].path, client.BlockSize)
+	})
+	hashed := 0
+	for i, job := range jobs {
+		if hashErrs[i] != nil {
+			log.Printf("Cannot hash file %s: %v", job.path, hashErrs[i])
+			continue
+		}
+		hashed++
+		compareLocalIndexFile(localFileInfoMap, job.info, blockHashLists[i], baseDir) // compare with local index file
+		// the stat data was taken before hashing, a write since changes it, unless it is too recent to tell
+		if job.info.ModTime().Before(scanStart.Add(-STAT_RACE_WINDOW)) {
+			fileStats[job.info.Name()] = &job.stat
+		}
+	}
 	checkLocalDelete(localFileInfoMap, baseDir) // mark deleted files
+	for filename := range fileStats {
+		if !present[filename] {
+			delete(fileStats, filename)
+		}
+	}
+	log.Printf("Hashed %d files, %d unchanged since they were last hashed", hashed, unchanged)
 	return err
 }
 
@@ -0,0 +1,504 @@
+package surfstore
+
+import (
+	"bytes"
+	context "context"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// testBlockStore is a BlockStore served over grpc on a local port
+type testBlockStore struct {
+	Addr       string
+	BlockStore *BlockStore
+	server     *grpc.Server
+}
+
+// Stop kills the block server, every call to it fails from then on
+func (s *testBlockStore) Stop() {
+	s.server.Stop()
+}
+
+func startTestBlockStores(t *testing.T, n int) []*testBlockStore {
+	t.Helper()
+	blockStores := make([]*testBlockStore, n)
+	for i := range blockStores {
+		blockStore := NewBlockStore()
+		server := grpc.NewServer()
+		RegisterBlockStoreServer(server, blockStore)
+		blockStores[i] = &testBlockStore{Addr: serveTest(t, server), BlockStore: blockStore, server: server}
+	}
+	return blockStores
+}
+
+// startTestMetaStore serves metaStore over grpc on a local port and returns its address
+func startTestMetaStore(t *testing.T, metaStore *MetaStore) string {
+	t.Helper()
+	server := grpc.NewServer()
+	RegisterMetaStoreServer(server, metaStore)
+	return serveTest(t, server)
+}
+
+func serveTest(t *testing.T, server *grpc.Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func testBlockStoreAddrs(blockStores []*testBlockStore) []string {
+	addrs := make([]string, len(blockStores))
+	for i, blockStore := range blockStores {
+		addrs[i] = blockStore.Addr
+	}
+	return addrs
+}
+
+// newTestClient returns a client of the MetaStore at metaAddr with an empty base directory of its own
+func newTestClient(t *testing.T, metaAddr string, blockSize int) RPCClient {
+	t.Helper()
+	client := NewSurfstoreRPCClient(metaAddr, t.TempDir(), blockSize)
+	client.Jobs = DEFAULT_CLIENT_JOBS
+	return client
+}
+
+// writeTestFiles writes files of random bytes with the given sizes into baseDir, and returns name -> contents
+func writeTestFiles(t *testing.T, baseDir string, sizes map[string]int) map[string][]byte {
+	t.Helper()
+	random := rand.New(rand.NewSource(int64(len(sizes))))
+	files := map[string][]byte{}
+	for name, size := range sizes {
+		data := make([]byte, size)
+		random.Read(data)
+		if err := os.WriteFile(filepath.Join(baseDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files[name] = data
+	}
+	return files
+}
+
+// checkTestFiles checks every file is in baseDir byte for byte
+func checkTestFiles(t *testing.T, baseDir string, files map[string][]byte) {
+	t.Helper()
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(baseDir, name))
+		if err != nil {
+			t.Fatalf("cannot read %s back: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s came back as %d different bytes, want %d", name, len(got), len(want))
+		}
+	}
+}
+
+func TestUploadReachesWriteQuorum(t *testing.T) {
+	blockStores := startTestBlockStores(t, 3)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ReplicationFactor = 3
+	metaAddr := startTestMetaStore(t, metaStore)
+
+	// the MetaStore does not probe, so it still lists the dead replica. It is first in the replica
+	// list of some blocks, so downloads have to move on to the next one
+	blockStores[0].Stop()
+	uploader := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, uploader.BaseDir, map[string]int{"a.bin": 10 * 1024, "b.bin": 3*1024 + 17})
+	ClientSync(uploader)
+
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := uploader.GetFileInfoMap(&fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		if fileInfoMap[name].GetVersion() != 1 {
+			t.Fatalf("%s was not uploaded with a replica down", name)
+		}
+	}
+	for _, blockStore := range blockStores[1:] {
+		for name := range files {
+			hashes, err := hashLocalFile(filepath.Join(uploader.BaseDir, name), uploader.BlockSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, hash := range hashes {
+				if ok, _ := blockStore.BlockStore.Storage.Has(hash); !ok {
+					t.Fatalf("the live replica %s is missing block %s of %s", blockStore.Addr, hash, name)
+				}
+			}
+		}
+	}
+
+	downloader := newTestClient(t, metaAddr, 1024)
+	ClientSync(downloader)
+	checkTestFiles(t, downloader.BaseDir, files)
+}
+
+func TestWriteQuorumCountsReplicasTheMetaStoreLeavesOut(t *testing.T) {
+	blockStores := startTestBlockStores(t, 3)
+	metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+	metaStore.ReplicationFactor = 3
+	ring, err := metaStore.GetBlockStoreAddrs(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quorum := writeQuorum(ring)
+	hash := GetBlockHashString([]byte("a"))
+	replicasWithDown := func(down ...string) []string {
+		health := newBlockStoreHealth()
+		for _, addr := range down {
+			health.records[addr] = &healthRecord{lastSeen: time.Now().Add(-BLOCKSTORE_DOWN_TIMEOUT)}
+		}
+		metaStore.health.Store(health)
+		blockStoreMap, err := metaStore.GetBlockStoreMap(context.Background(), &BlockHashes{Hashes: []string{hash}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas := []string{}
+		for addr := range blockStoreMap.BlockStoreMap {
+			replicas = append(replicas, addr)
+		}
+		return replicas
+	}
+
+	live := replicasWithDown(blockStores[0].Addr)
+	if err := checkWriteQuorum(map[string][]string{hash: live}, map[string]error{}, quorum); err != nil {
+		t.Fatalf("both live replicas of 3 got the block: %v", err)
+	}
+	if err := checkWriteQuorum(map[string][]string{hash: live}, map[string]error{live[0]: errors.New("down")}, quorum); err == nil {
+		t.Fatal("a block on 1 of 3 replicas reached the write quorum")
+	}
+	// the only replica listed is a majority of one, not of the 3 the ring keeps
+	live = replicasWithDown(blockStores[0].Addr, blockStores[1].Addr)
+	if err := checkWriteQuorum(map[string][]string{hash: live}, map[string]error{}, quorum); err == nil {
+		t.Fatalf("a block on %v, 1 of 3 replicas, reached the write quorum", live)
+	}
+}
+
+func TestBlockReplicasInRingOrder(t *testing.T) {
+	zones := map[string]string{"a:1": "east", "b:1": "east", "c:1": "west", "d:1": "west"}
+	for _, blockStores := range []*BlockStoreAddrs{
+		{Placement: PLACEMENT_CONSISTENT},
+		{Placement: PLACEMENT_RENDEZVOUS},
+		{Placement: PLACEMENT_BOUNDED},
+		{Placement: PLACEMENT_CONSISTENT, Zones: zones},
+	} {
+		blockStores.BlockStoreAddrs = []string{"a:1", "b:1", "c:1", "d:1"}
+		blockStores.VirtualNodes, blockStores.Replication = 4, 2
+		placement := blockStores.Placement
+		ring := newBlockStoreRing(blockStores)
+		blockStoreMap := map[string][]string{}
+		want := map[string][]string{}
+		for i := 0; i < 100; i++ {
+			hash := GetBlockHashString([]byte(strconv.Itoa(i)))
+			want[hash] = ring.responsibleServers(hash, 2)
+			for _, serverAddr := range want[hash] {
+				blockStoreMap[serverAddr] = append(blockStoreMap[serverAddr], hash, hash)
+			}
+		}
+		for hash, got := range blockReplicas(blockStoreMap, blockStores) {
+			if !CompareBlockHashList(got, want[hash]) {
+				t.Fatalf("%s: replicas of %s are %v, the ring has %v", placement, hash, got, want[hash])
+			}
+		}
+	}
+}
+
+// downloadTestBlocks puts n blocks on every one of blockStores, and returns their hashes and
+// hash -> data
+func downloadTestBlocks(t *testing.T, blockStores []*testBlockStore, n int) ([]string, map[string][]byte) {
+	t.Helper()
+	hashes := make([]string, n)
+	blocks := map[string][]byte{}
+	for i := range hashes {
+		data := []byte("block " + strconv.Itoa(i))
+		hashes[i] = GetBlockHashString(data)
+		blocks[hashes[i]] = data
+		for _, blockStore := range blockStores {
+			if err := blockStore.BlockStore.Storage.Put(hashes[i], &Block{BlockData: data, BlockSize: int32(len(data))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return hashes, blocks
+}
+
+// downloadTestReplicas lists every hash on blockStores, in that order
+func downloadTestReplicas(hashes []string, blockStores []*testBlockStore) map[string][]string {
+	replicas := map[string][]string{}
+	for _, hash := range hashes {
+		replicas[hash] = testBlockStoreAddrs(blockStores)
+	}
+	return replicas
+}
+
+// checkDownloadedBlocks downloads hashes and checks they come in order, with their data
+func checkDownloadedBlocks(t *testing.T, hashes []string, replicas map[string][]string, blocks map[string][]byte) {
+	t.Helper()
+	got := make([][]byte, 0, len(hashes))
+	err := downloadBlocks(RPCClient{}, hashes, replicas, func(block *Block) error {
+		got = append(got, block.BlockData)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(hashes) {
+		t.Fatalf("downloaded %d blocks, want %d", len(got), len(hashes))
+	}
+	for i, hash := range hashes {
+		if !bytes.Equal(got[i], blocks[hash]) {
+			t.Fatalf("block %d came back as %q, want %q", i, got[i], blocks[hash])
+		}
+	}
+}
+
+func TestDownloadFallsBackOnMissingBlocksAndWritesThemBack(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	hashes, blocks := downloadTestBlocks(t, blockStores, 10)
+	first := blockStores[0].BlockStore
+	for _, i := range []int{3, 7} {
+		if err := first.Storage.Delete(hashes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkDownloadedBlocks(t, hashes, downloadTestReplicas(hashes, blockStores), blocks)
+	for _, i := range []int{3, 7} {
+		block, exists, _ := first.Storage.Get(hashes[i])
+		if !exists || !bytes.Equal(block.BlockData, blocks[hashes[i]]) {
+			t.Fatalf("block %d was not written back to the replica that did not have it", i)
+		}
+	}
+}
+
+func TestDownloadFallsBackOnCorruptBlocks(t *testing.T) {
+	blockStores := startTestBlockStores(t, 2)
+	hashes, blocks := downloadTestBlocks(t, blockStores, 10)
+	// the first replica sends other bytes under the hash of block 5
+	rotten := []byte("rotten")
+	if err := blockStores[0].BlockStore.Storage.Put(hashes[5], &Block{BlockData: rotten, BlockSize: int32(len(rotten))}); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloadedBlocks(t, hashes, downloadTestReplicas(hashes, blockStores), blocks)
+
+	// with the block rotten on every replica, nothing is written past the blocks before it
+	if err := blockStores[1].BlockStore.Storage.Put(hashes[5], &Block{BlockData: rotten, BlockSize: int32(len(rotten))}); err != nil {
+		t.Fatal(err)
+	}
+	written := 0
+	err := downloadBlocks(RPCClient{}, hashes, downloadTestReplicas(hashes, blockStores), func(block *Block) error {
+		written++
+		return nil
+	})
+	if err == nil || written != 5 {
+		t.Fatalf("wrote %d blocks with block 5 rotten everywhere, error %v", written, err)
+	}
+}
+
+func TestSyncKeepsUserTempFilesAndSkipsDownloads(t *testing.T) {
+	blockStores := startTestBlockStores(t, 1)
+	metaAddr := startTestMetaStore(t, NewMetaStore(testBlockStoreAddrs(blockStores)))
+
+	client := newTestClient(t, metaAddr, 1024)
+	files := writeTestFiles(t, client.BaseDir, map[string]int{".tmp-notes": 100, "a-b.bin": 2000})
+	ClientSync(client)
+
+	// a download of a-b.bin that did not finish, and one of a file the index does not have yet
+	leftover := filepath.Join(client.BaseDir, downloadFilePrefix+"a-b.bin-1234")
+	pending := filepath.Join(client.BaseDir, downloadFilePrefix+"c.bin-5678")
+	for _, path := range []string{leftover, pending} {
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ClientSync(client)
+	checkTestFiles(t, client.BaseDir, files)
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("the leftover download of an indexed file is still there: %v", err)
+	}
+	if _, err := os.Stat(pending); err != nil {
+		t.Fatalf("a temp file of a file not in the index was removed: %v", err)
+	}
+
+	fileInfoMap := map[string]*FileMetaData{}
+	if err := client.GetFileInfoMap(&fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fileInfoMap[".tmp-notes"]; !ok {
+		t.Fatal(".tmp-notes was not uploaded")
+	}
+	for name := range fileInfoMap {
+		if strings.HasPrefix(name, downloadFilePrefix) {
+			t.Fatalf("the download temp file %s was uploaded", name)
+		}
+	}
+}
+
+func TestIsDownloadLeftover(t *testing.T) {
+	index := map[string]*FileMetaData{"a.txt": {}, "a-b.txt": {}}
+	for name, want := range map[string]bool{
+		downloadFilePrefix + "a.txt-123": true,
+		downloadFilePrefix + "a-b.txt-9": true,
+		downloadFilePrefix + "a.txt-":    false,
+		downloadFilePrefix + "a.txt-12x": false,
+		downloadFilePrefix + "b.txt-123": false,
+		downloadFilePrefix + "a.txt":     false,
+		".tmp-a.txt-123":                 false,
+	} {
+		if got := isDownloadLeftover(name, index); got != want {
+			t.Fatalf("isDownloadLeftover(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestUnchangedStatDataSkipsTheRehashUnlessParanoid(t *testing.T) {
+	client := RPCClient{BaseDir: t.TempDir(), BlockSize: 1024, Jobs: 1}
+	path := filepath.Join(client.BaseDir, "a.txt")
+	// old enough for its stat data to be kept
+	mtime := time.Now().Add(-time.Hour)
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	localFileInfoMap := map[string]*FileMetaData{}
+	fileStats := map[string]*FileStat{}
+	scan := func(wantVersion int32, wantData string) {
+		t.Helper()
+		if err := updateLocalIndexFile(client, nil, client.BaseDir, localFileInfoMap, fileStats); err != nil {
+			t.Fatal(err)
+		}
+		fileMetaData := localFileInfoMap["a.txt"]
+		if fileMetaData.Version != wantVersion || !CompareBlockHashList(fileMetaData.BlockHashList, []string{GetBlockHashString([]byte(wantData))}) {
+			t.Fatalf("a.txt is indexed at version %d with %v, want version %d of %q", fileMetaData.Version, fileMetaData.BlockHashList, wantVersion, wantData)
+		}
+		if fileStats["a.txt"] == nil {
+			t.Fatal("the stat data of a.txt was not kept")
+		}
+	}
+
+	write("first")
+	scan(1, "first")
+	// other bytes of the same size, in place and with the old modification time: the stat data
+	// does not tell, so the file is not read again
+	write("fiRST")
+	scan(1, "first")
+	client.Paranoid = true
+	scan(2, "fiRST")
+	client.Paranoid = false
+	// a file of another size is hashed again
+	write("second")
+	scan(3, "second")
+}
+
+// Run with -race, the workers of a parallel sync share the maps of the sync run
+func TestWorkerPoolRunsEveryJobOnceWithinTheLimit(t *testing.T) {
+	for _, jobs := range []int{0, 1, 4, 100} {
+		const n = 1000
+		calls := make([]atomic.Int32, n)
+		var running, most atomic.Int32
+		runJobs(jobs, n, func(i int) {
+			now := running.Add(1)
+			for m := most.Load(); now > m && !most.CompareAndSwap(m, now); m = most.Load() {
+			}
+			calls[i].Add(1)
+			time.Sleep(10 * time.Microsecond)
+			running.Add(-1)
+		})
+		for i := range calls {
+			if calls[i].Load() != 1 {
+				t.Fatalf("-j %d: job %d ran %d times", jobs, i, calls[i].Load())
+			}
+		}
+		if limit := int32(max(jobs, 1)); most.Load() > limit {
+			t.Fatalf("-j %d: %d jobs ran at once", jobs, most.Load())
+		}
+	}
+
+	// every put writes the entry of its own server in uploaded while the others run
+	servers := testServers(20)
+	uploaded := syncedBlocks{}
+	forEachServer(RPCClient{Jobs: 8}, servers, uploaded, func(serverAddr string) {
+		for i := 0; i < 100; i++ {
+			uploaded[serverAddr][strconv.Itoa(i)] = true
+		}
+	})
+	for _, serverAddr := range servers {
+		if len(uploaded[serverAddr]) != 100 {
+			t.Fatalf("%s has %d blocks, want 100", serverAddr, len(uploaded[serverAddr]))
+		}
+	}
+}
+
+func TestParallelSyncMatchesSerialSync(t *testing.T) {
+	files := writeTestFiles(t, t.TempDir(), map[string]int{"a.bin": 40 * 1024, "b.bin": 9*1024 + 5, "c.bin": 1024, "d.bin": 0, "e.bin": 17 * 1024})
+	// the index and where every block went, after a sync with -j jobs
+	syncWith := func(jobs int) (map[string]*FileMetaData, map[string]int) {
+		blockStores := startTestBlockStores(t, 3)
+		metaStore := NewMetaStore(testBlockStoreAddrs(blockStores))
+		metaStore.ReplicationFactor = 2
+		client := newTestClient(t, startTestMetaStore(t, metaStore), 1024)
+		client.Jobs = jobs
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(client.BaseDir, name), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ClientSync(client)
+		index, err := LoadMetaFromMetaFile(client.BaseDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the servers have other addresses every time, so count the copies of every block
+		copies := map[string]int{}
+		for _, blockStore := range blockStores {
+			hashes, err := blockStore.BlockStore.Storage.Hashes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, hash := range hashes {
+				copies[hash]++
+			}
+		}
+		return index, copies
+	}
+
+	serialIndex, serialCopies := syncWith(1)
+	parallelIndex, parallelCopies := syncWith(4)
+	if len(parallelIndex) != len(serialIndex) {
+		t.Fatalf("%d files in the index with -j 4, %d with -j 1", len(parallelIndex), len(serialIndex))
+	}
+	for name, want := range serialIndex {
+		got := parallelIndex[name]
+		if got == nil || got.Version != want.Version || !CompareBlockHashList(got.BlockHashList, want.BlockHashList) {
+			t.Fatalf("%s is indexed differently with -j 4 than with -j 1", name)
+		}
+	}
+	if len(parallelCopies) != len(serialCopies) {
+		t.Fatalf("%d blocks uploaded with -j 4, %d with -j 1", len(parallelCopies), len(serialCopies))
+	}
+	for hash, want := range serialCopies {
+		if parallelCopies[hash] != want {
+			t.Fatalf("block %s has %d copies with -j 4, %d with -j 1", hash, parallelCopies[hash], want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package surfstore
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SyncJournal makes a sync durable one operation at a time. ClientSync writes index.db once,
+// at the end, so a client that dies halfway would forget the uploads the MetaStore already took
+// and the downloads already renamed into place, and the next sync would see conflicts that are not.
+// Before an operation its intent (the index entry of the file once it is done) is appended to
+// the journal, and a done record once it is done. The next sync replays the journal into index.db
+// before anything else: a done operation is rolled forward, an operation without a done record
+// is rolled forward only if it did happen (the MetaStore has that version of the file, or the
+// base directory has those blocks) and rolled back, dropped, otherwise
+type SyncJournal struct {
+	wal     *WriteAheadLog
+	records []*SyncJournalRecord
+	nextId  int64
+}
+
+// OpenSyncJournal opens the journal in baseDir, with the records of the sync it interrupted if any
+func OpenSyncJournal(baseDir string) (*SyncJournal, error) {
+	wal, payloads, err := OpenWriteAheadLog(filepath.Join(baseDir, DEFAULT_JOURNAL_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*SyncJournalRecord, 0, len(payloads))
+	for _, payload := range payloads {
+		record := &SyncJournalRecord{}
+		if err := proto.Unmarshal(payload, record); err != nil {
+			wal.Close()
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return &SyncJournal{wal: wal, records: records}, nil
+}
+
+// Replay applies the operations of the interrupted sync to localFileInfoMap and writes it to
+// index.db, then empties the journal
+func (j *SyncJournal) Replay(client RPCClient, localFileInfoMap map[string]*FileMetaData) error {
+	if len(j.records) == 0 {
+		return nil
+	}
+	done := make(map[int64]bool)
+	for _, record := range j.records {
+		if record.Done {
+			done[record.Id] = true
+		}
+	}
+	// the MetaStore is only asked if an UpdateFile may or may not have happened
+	var remoteIndex map[string]*FileMetaData
+	for _, record := range j.records {
+		if record.Done || record.FileMetaData == nil {
+			continue
+		}
+		fileMetaData := record.FileMetaData
+		happened := done[record.Id]
+		if !happened && record.Remote {
+			if remoteIndex == nil {
+				remoteIndex = make(map[string]*FileMetaData)
+				if err := client.GetFileInfoMap(&remoteIndex); err != nil {
+					return err
+				}
+			}
+			remote, ok := remoteIndex[fileMetaData.Filename]
+			happened = ok && remote.Version == fileMetaData.Version && CompareBlockHashList(remote.BlockHashList, fileMetaData.BlockHashList)
+		} else if !happened {
+			var err error
+			if happened, err = localFileIs(client, fileMetaData); err != nil {
+				return err
+			}
+		}
+		if happened {
+			log.Println("Journal: rolling forward", fileMetaData.Filename, "version", fileMetaData.Version)
+			localFileInfoMap[fileMetaData.Filename] = fileMetaData
+		} else {
+			log.Println("Journal: rolling back", fileMetaData.Filename, "version", fileMetaData.Version)
+		}
+	}
+	if err := WriteMetaFile(localFileInfoMap, client.BaseDir); err != nil {
+		return err
+	}
+	return j.Reset()
+}
+
+// localFileIs tells whether the file in the base directory is the one fileMetaData describes
+func localFileIs(client RPCClient, fileMetaData *FileMetaData) (bool, error) {
+	path := filepath.Join(client.BaseDir, fileMetaData.Filename)
+	if fileMetaData.BlockHashList[0] == TOMBSTONE_HASHVALUE {
+		_, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	blockHashList, err := hashLocalFile(path, client.BlockSize)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return CompareBlockHashList(blockHashList, fileMetaData.BlockHashList), nil
+}
+
+// Begin appends the intent of an operation that leaves the file as fileMetaData, and returns its id
+func (j *SyncJournal) Begin(remote bool, fileMetaData *FileMetaData) (int64, error) {
+	id := j.nextId
+	j.nextId++
+	return id, j.append(&SyncJournalRecord{Id: id, Remote: remote, FileMetaData: fileMetaData})
+}
+
+// Done appends the done record of the operation Begin returned id for
+func (j *SyncJournal) Done(id int64) error {
+	return j.append(&SyncJournalRecord{Id: id, Done: true})
+}
+
+func (j *SyncJournal) append(record *SyncJournalRecord) error {
+	payload, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return j.wal.Append(payload)
+}
+
+// Reset drops every record, once index.db has every operation in it
+func (j *SyncJournal) Reset() error {
+	j.records = nil
+	return j.wal.Reset()
+}
+
+func (j *SyncJournal) Close() error {
+	return j.wal.Close()
+}
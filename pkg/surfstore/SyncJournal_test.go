@@ -0,0 +1,90 @@
+package surfstore
+
+import (
+	context "context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncJournalReplayRollsForwardWhatHappened(t *testing.T) {
+	metaStore := NewMetaStore([]string{})
+	client := newTestClient(t, startTestMetaStore(t, metaStore), 1024)
+	journal, err := OpenSyncJournal(client.BaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin := func(remote bool, fileMetaData *FileMetaData) int64 {
+		t.Helper()
+		id, err := journal.Begin(remote, fileMetaData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// done, rolled forward whatever the MetaStore or the base directory have
+	if err := journal.Done(begin(false, &FileMetaData{Filename: "done.txt", Version: 2, BlockHashList: []string{"h"}})); err != nil {
+		t.Fatal(err)
+	}
+	// an upload the MetaStore took, and one it never got
+	uploaded := &FileMetaData{Filename: "uploaded.txt", Version: 1, BlockHashList: []string{"h1"}}
+	begin(true, uploaded)
+	if _, err := metaStore.UpdateFile(context.Background(), uploaded); err != nil {
+		t.Fatal(err)
+	}
+	begin(true, &FileMetaData{Filename: "lost.txt", Version: 3, BlockHashList: []string{"h3"}})
+	// a download renamed into place, one that was not, and a deletion that happened
+	data := []byte("downloaded")
+	if err := os.WriteFile(filepath.Join(client.BaseDir, "downloaded.txt"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	begin(false, &FileMetaData{Filename: "downloaded.txt", Version: 4, BlockHashList: []string{GetBlockHashString(data)}})
+	begin(false, &FileMetaData{Filename: "missing.txt", Version: 5, BlockHashList: []string{GetBlockHashString([]byte("never written"))}})
+	begin(false, &FileMetaData{Filename: "deleted.txt", Version: 6, BlockHashList: []string{TOMBSTONE_HASHVALUE}})
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next sync finds the journal of the one that was interrupted
+	journal, err = OpenSyncJournal(client.BaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lostBefore := &FileMetaData{Filename: "lost.txt", Version: 2, BlockHashList: []string{"h2"}}
+	localFileInfoMap := map[string]*FileMetaData{"lost.txt": lostBefore}
+	if err := journal.Replay(client, localFileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int32{"done.txt": 2, "uploaded.txt": 1, "lost.txt": 2, "downloaded.txt": 4, "deleted.txt": 6}
+	index, err := LoadMetaFromMetaFile(client.BaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, fileInfoMap := range map[string]map[string]*FileMetaData{"the local index": localFileInfoMap, "index.db": index} {
+		if len(fileInfoMap) != len(want) {
+			t.Fatalf("%s has %d files after the replay, want %d", name, len(fileInfoMap), len(want))
+		}
+		for filename, version := range want {
+			if fileInfoMap[filename].GetVersion() != version {
+				t.Fatalf("%s has %s at version %d after the replay, want %d", name, filename, fileInfoMap[filename].GetVersion(), version)
+			}
+		}
+	}
+	if !CompareBlockHashList(localFileInfoMap["lost.txt"].BlockHashList, lostBefore.BlockHashList) {
+		t.Fatal("the upload the MetaStore never got was not rolled back")
+	}
+
+	// the replay emptied the journal
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	journal, err = OpenSyncJournal(client.BaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if len(journal.records) != 0 {
+		t.Fatalf("%d records are left in the journal after the replay", len(journal.records))
+	}
+}
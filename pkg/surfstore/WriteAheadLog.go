@@ -0,0 +1,160 @@
+package surfstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteAheadLog is an append-only file of records. Every record is framed as
+// [4 byte length][4 byte crc32][payload] and fsync'd before Append returns.
+// A torn record at the end of the file (crash in the middle of a write) is dropped on open,
+// and so is anything after it that does not frame a record, like a zero-filled tail.
+type WriteAheadLog struct {
+	Path string
+	file *os.File
+}
+
+const walHeaderSize int = 8
+
+// the largest payload a record may have. A header asking for more is garbage, not a record,
+// and is not trusted with an allocation. Payloads are never empty: a zero-filled tail would
+// frame empty records, the crc32 of no bytes being 0
+const walMaxRecordSize int = 256 << 20
+
+// OpenWriteAheadLog opens (or creates) the log at path and returns the payload of every complete record in it
+func OpenWriteAheadLog(path string) (*WriteAheadLog, [][]byte, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, validLen, err := readWalRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	// cut off a torn tail so new records are appended after the last good one
+	if err = file.Truncate(validLen); err == nil {
+		_, err = file.Seek(validLen, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if err = syncDir(filepath.Dir(path)); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return &WriteAheadLog{Path: path, file: file}, records, nil
+}
+
+// Append writes records and fsyncs them once
+func (w *WriteAheadLog) Append(payloads ...[]byte) error {
+	if err := checkWalPayloads(payloads); err != nil {
+		return err
+	}
+	if _, err := w.file.Write(encodeWalRecords(payloads)); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Reset drops every record, used once the records are covered by a snapshot
+func (w *WriteAheadLog) Reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Rewrite atomically replaces every record with payloads
+func (w *WriteAheadLog) Rewrite(payloads [][]byte) error {
+	if err := checkWalPayloads(payloads); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(w.Path, encodeWalRecords(payloads)); err != nil {
+		return err
+	}
+	// the old file handle points at the replaced file
+	file, err := os.OpenFile(w.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+	w.file.Close()
+	w.file = file
+	return nil
+}
+
+func (w *WriteAheadLog) Close() error {
+	return w.file.Close()
+}
+
+// checkWalPayloads refuses the payloads readWalRecords would not read back
+func checkWalPayloads(payloads [][]byte) error {
+	for _, payload := range payloads {
+		if len(payload) == 0 || len(payload) > walMaxRecordSize {
+			return fmt.Errorf("write-ahead log record of %d bytes, must be 1 to %d bytes", len(payload), walMaxRecordSize)
+		}
+	}
+	return nil
+}
+
+func encodeWalRecords(payloads [][]byte) []byte {
+	size := 0
+	for _, payload := range payloads {
+		size += walHeaderSize + len(payload)
+	}
+	records := make([]byte, 0, size)
+	for _, payload := range payloads {
+		header := make([]byte, walHeaderSize)
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+		records = append(records, header...)
+		records = append(records, payload...)
+	}
+	return records
+}
+
+func readWalRecords(file *os.File) ([][]byte, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	records := make([][]byte, 0)
+	validLen := int64(0)
+	header := make([]byte, walHeaderSize)
+	for {
+		if _, err := io.ReadFull(file, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, validLen, nil
+			}
+			return nil, 0, err
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length == 0 || length > uint32(walMaxRecordSize) {
+			return records, validLen, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(file, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, validLen, nil
+			}
+			return nil, 0, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return records, validLen, nil
+		}
+		records = append(records, payload)
+		validLen += int64(walHeaderSize + len(payload))
+	}
+}
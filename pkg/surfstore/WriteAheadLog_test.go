@@ -0,0 +1,117 @@
+package surfstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAheadLogDropsGarbageTail(t *testing.T) {
+	tails := map[string][]byte{
+		"zero filled": make([]byte, 64),
+		"torn record": encodeWalRecords([][]byte{[]byte("torn record")})[:walHeaderSize+4],
+		"huge length": func() []byte {
+			header := make([]byte, walHeaderSize)
+			binary.BigEndian.PutUint32(header[0:4], 0xffffffff)
+			return header
+		}(),
+		"bad crc": func() []byte {
+			record := encodeWalRecords([][]byte{[]byte("bad crc")})
+			record[len(record)-1] ^= 0xff
+			return record
+		}(),
+	}
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.wal")
+			wal, _, err := OpenWriteAheadLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := wal.Append([]byte("one"), []byte("two")); err != nil {
+				t.Fatal(err)
+			}
+			wal.Close()
+			appendToFile(t, path, tail)
+
+			wal, records, err := OpenWriteAheadLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkWalRecords(t, records, "one", "two")
+			// the garbage is cut off, so a new record is read back after the old ones
+			if err := wal.Append([]byte("three")); err != nil {
+				t.Fatal(err)
+			}
+			wal.Close()
+			wal, records, err = OpenWriteAheadLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer wal.Close()
+			checkWalRecords(t, records, "one", "two", "three")
+		})
+	}
+}
+
+func TestWriteAheadLogRefusesEmptyRecords(t *testing.T) {
+	wal, _, err := OpenWriteAheadLog(filepath.Join(t.TempDir(), "test.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if err := wal.Append([]byte{}); err == nil {
+		t.Fatal("Append took an empty record")
+	}
+	if err := wal.Rewrite([][]byte{[]byte("one"), nil}); err == nil {
+		t.Fatal("Rewrite took an empty record")
+	}
+}
+
+func TestMetaStoreLogIgnoresZeroFilledTail(t *testing.T) {
+	dir := t.TempDir()
+	metaLog, _, err := OpenMetaStoreLog(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := metaLog.Append(&FileMetaData{Filename: "a.txt", Version: 1, BlockHashList: []string{"h"}}); err != nil {
+		t.Fatal(err)
+	}
+	metaLog.Close()
+	appendToFile(t, filepath.Join(dir, metaWalFilename), make([]byte, 4096))
+
+	metaLog, fileMetaMap, err := OpenMetaStoreLog(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metaLog.Close()
+	if len(fileMetaMap) != 1 || fileMetaMap["a.txt"].GetVersion() != 1 {
+		t.Fatalf("replayed %v, want only a.txt at version 1", fileMetaMap)
+	}
+}
+
+func appendToFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkWalRecords(t *testing.T, records [][]byte, want ...string) {
+	t.Helper()
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(records[i], []byte(want[i])) {
+			t.Fatalf("record %d is %q, want %q", i, records[i], want[i])
+		}
+	}
+}